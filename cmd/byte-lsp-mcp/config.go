@@ -0,0 +1,71 @@
+package main
+
+import (
+	"time"
+
+	"github.com/dreamcats/bytelsp/internal/config"
+	"github.com/dreamcats/bytelsp/internal/gopls"
+	"github.com/dreamcats/bytelsp/internal/mcp"
+	"github.com/dreamcats/bytelsp/internal/workspace"
+)
+
+// loadConfig loads the config file given with -config, or the one found at
+// the workspace root of the working directory. Without either, it returns
+// an empty config (all defaults).
+func loadConfig(path string) (*config.Config, error) {
+	if path == "" {
+		root, err := workspace.DetectRoot(".")
+		if err != nil {
+			return nil, err
+		}
+		path = config.Find(root)
+		if path == "" {
+			return &config.Config{}, nil
+		}
+	}
+	return config.Load(path)
+}
+
+// workspaceRoot returns root, or the root detected from the working directory.
+func workspaceRoot(root string) (string, error) {
+	if root != "" {
+		return root, nil
+	}
+	return workspace.DetectRoot(".")
+}
+
+// serviceOptions maps the config file (and merged gopls settings) to service options.
+func serviceOptions(cfg *config.Config, settings *gopls.Settings) mcp.Options {
+	return mcp.Options{
+		Root:               cfg.Workspace.Root,
+		ExtraRoots:         cfg.Workspace.ExtraRoots,
+		GoplsPath:          cfg.Gopls.Path,
+		GoplsArgs:          cfg.Gopls.Args,
+		GoplsSettings:      settings,
+		GoplsRemote:        cfg.Gopls.Remote,
+		GoplsAutoStart:     cfg.Gopls.AutoStart,
+		InitTimeout:        cfg.Timeouts.Init.Std(),
+		RequestTimeout:     cfg.Timeouts.Request.Std(),
+		IndexWait:          indexWait(cfg.Timeouts.IndexWait),
+		WarmupTimeout:      cfg.Timeouts.Warmup.Std(),
+		MaxSessions:        cfg.Limits.MaxSessions,
+		SessionIdleTimeout: cfg.Timeouts.SessionIdle.Std(),
+		MaxReferences:      cfg.Limits.MaxReferences,
+		SourceMaxLines:     cfg.Limits.SourceMaxLines,
+		SourceMaxChars:     cfg.Limits.SourceMaxChars,
+		EnabledTools:       cfg.Tools.Enabled,
+		DisabledTools:      cfg.Tools.Disabled,
+	}
+}
+
+// indexWait maps timeouts.index_wait to mcp.Options.IndexWait: unset keeps
+// the default, and an explicit 0 turns the wait off.
+func indexWait(d *config.Duration) time.Duration {
+	switch {
+	case d == nil:
+		return 0
+	case *d == 0:
+		return -1
+	}
+	return d.Std()
+}
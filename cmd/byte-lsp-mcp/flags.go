@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dreamcats/bytelsp/internal/gopls"
+)
+
+// listFlag collects the values of a repeatable flag.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// goplsFlags are the command-line flags that become gopls settings.
+type goplsFlags struct {
+	settingsFile     string
+	tags             string
+	buildFlags       listFlag
+	env              listFlag
+	directoryFilters listFlag
+	analyzers        listFlag
+	staticcheck      bool
+}
+
+func (f *goplsFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.settingsFile, "gopls-settings", "", "JSON file with gopls settings (same keys as editor config)")
+	fs.StringVar(&f.tags, "tags", "", "comma-separated build tags, e.g. integration,e2e")
+	fs.Var(&f.buildFlags, "build-flag", "gopls build flag (repeatable)")
+	fs.Var(&f.env, "env", "KEY=VALUE environment for the go command run by gopls (repeatable)")
+	fs.Var(&f.directoryFilters, "directory-filter", "gopls directory filter, e.g. -node_modules (repeatable)")
+	fs.Var(&f.analyzers, "analyzer", "enable or disable an analyzer: name or name=false (repeatable)")
+	fs.BoolVar(&f.staticcheck, "staticcheck", false, "enable staticcheck analyzers in gopls")
+}
+
+// settings builds gopls settings from the flags, on top of base (from the
+// config file, may be nil). It returns nil when there is nothing to pass.
+func (f *goplsFlags) settings(fs *flag.FlagSet, base *gopls.Settings) (*gopls.Settings, error) {
+	settings := &gopls.Settings{}
+	used := base != nil
+	settings.Merge(base)
+	if f.settingsFile != "" {
+		loaded, err := gopls.LoadSettingsFile(f.settingsFile)
+		if err != nil {
+			return nil, err
+		}
+		settings.Merge(loaded)
+		used = true
+	}
+
+	fromFlags := &gopls.Settings{BuildFlags: f.buildFlags, DirectoryFilters: f.directoryFilters}
+	if f.tags != "" {
+		fromFlags.BuildFlags = append(fromFlags.BuildFlags, "-tags="+f.tags)
+	}
+	for _, kv := range f.env {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("-env %q: want KEY=VALUE", kv)
+		}
+		if fromFlags.Env == nil {
+			fromFlags.Env = make(map[string]string)
+		}
+		fromFlags.Env[key] = value
+	}
+	for _, a := range f.analyzers {
+		name, value, hasValue := strings.Cut(a, "=")
+		enabled := true
+		if hasValue {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("-analyzer %q: %v", a, err)
+			}
+			enabled = b
+		}
+		if fromFlags.Analyses == nil {
+			fromFlags.Analyses = make(map[string]bool)
+		}
+		fromFlags.Analyses[name] = enabled
+	}
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "staticcheck":
+			fromFlags.Staticcheck = &f.staticcheck
+			used = true
+		case "tags", "build-flag", "env", "directory-filter", "analyzer":
+			used = true
+		}
+	})
+	settings.Merge(fromFlags)
+	if !used {
+		return nil, nil
+	}
+	return settings, nil
+}
@@ -9,6 +9,7 @@ import (
 
 	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
 
+	"github.com/dreamcats/bytelsp/internal/gopls"
 	"github.com/dreamcats/bytelsp/internal/mcp"
 )
 
@@ -21,6 +22,13 @@ func main() {
 	showVersion := flag.Bool("version", false, "print version and exit")
 	showHelp := flag.Bool("h", false, "print help and exit")
 	flag.BoolVar(showHelp, "help", false, "print help and exit")
+	configPath := flag.String("config", "", "config file (default: .byte-lsp.yaml at the workspace root)")
+	remote := flag.String("remote", "", "share a gopls daemon: auto, host:port or unix;/path")
+	autoStart := flag.Bool("remote-autostart", false, "start a gopls daemon on -remote if none is listening")
+	record := flag.String("record", "", "record all LSP traffic with gopls to FILE")
+	replay := flag.String("replay", "", "serve gopls responses from a recording made with -record")
+	var gf goplsFlags
+	gf.register(flag.CommandLine)
 	flag.Parse()
 
 	if *showHelp {
@@ -32,8 +40,44 @@ func main() {
 		return
 	}
 
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	if *remote != "" {
+		cfg.Gopls.Remote = *remote
+	}
+	if *autoStart {
+		cfg.Gopls.AutoStart = true
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid flags: %v", err)
+	}
+	base, err := cfg.GoplsSettings()
+	if err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	settings, err := gf.settings(flag.CommandLine, base)
+	if err != nil {
+		log.Fatalf("invalid gopls settings: %v", err)
+	}
+
+	opts := serviceOptions(cfg, settings)
+	opts.RecordPath = *record
+	if *replay != "" {
+		root, err := workspaceRoot(opts.Root)
+		if err != nil {
+			log.Fatalf("replay: %v", err)
+		}
+		replayer, err := gopls.NewReplayer(*replay, root)
+		if err != nil {
+			log.Fatalf("replay: %v", err)
+		}
+		opts.GoplsDial = replayer.Dial
+	}
+
 	ctx := context.Background()
-	service, err := mcp.NewService(ctx)
+	service, err := mcp.NewService(ctx, opts)
 	if err != nil {
 		log.Fatalf("failed to create service: %v", err)
 	}
@@ -44,7 +88,7 @@ func main() {
 		Title:      "Byte LSP MCP (gopls-based Go analysis)",
 		Version:    version,
 		WebsiteURL: "https://github.com/dreamcats/bytelsp",
-	}, nil)
+	}, service.ServerOptions())
 	service.Register(server)
 
 	if err := server.Run(ctx, &sdk.StdioTransport{}); err != nil {
@@ -59,8 +103,30 @@ Usage:
   byte-lsp-mcp [flags]
 
 Flags:
-  -h, -help     show help
-  -version      show version
+  -h, -help                 show help
+  -version                  show version
+  -config FILE              config file (default: .byte-lsp.yaml, .byte-lsp.yml or
+                            .byte-lsp.json at the workspace root)
+
+gopls daemon:
+  -remote ADDR              share a gopls daemon with editors instead of running a
+                            private gopls: auto (gopls -remote=auto), host:port,
+                            unix;/path/to/socket or tcp;host:port
+  -remote-autostart         start gopls serve -listen=ADDR if nothing listens on -remote
+
+Debugging:
+  -record FILE              record every LSP frame exchanged with gopls (JSON lines,
+                            workspace root written as $ROOT)
+  -replay FILE              answer from a recording instead of running gopls
+
+gopls settings:
+  -tags a,b                 build tags (adds -tags=a,b to buildFlags)
+  -build-flag FLAG          gopls build flag (repeatable)
+  -env KEY=VALUE            environment for the go command run by gopls (repeatable)
+  -directory-filter FILTER  skip or include directories, e.g. -node_modules (repeatable)
+  -analyzer NAME[=false]    enable or disable an analyzer (repeatable)
+  -staticcheck              enable staticcheck analyzers
+  -gopls-settings FILE      JSON file with any gopls settings, merged with the flags above
 
 MCP config example (Claude Code / Desktop):
 {
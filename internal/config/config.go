@@ -0,0 +1,218 @@
+// Package config loads the byte-lsp-mcp project configuration file.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/dreamcats/bytelsp/internal/gopls"
+)
+
+// FileNames are the config file names looked up at the workspace root, in order.
+var FileNames = []string{".byte-lsp.yaml", ".byte-lsp.yml", ".byte-lsp.json"}
+
+// Config is the content of a .byte-lsp.yaml (or JSON) file.
+// Zero values mean "use the built-in default".
+type Config struct {
+	Gopls     GoplsConfig     `yaml:"gopls"`
+	Timeouts  TimeoutsConfig  `yaml:"timeouts"`
+	Limits    LimitsConfig    `yaml:"limits"`
+	Tools     ToolsConfig     `yaml:"tools"`
+	Workspace WorkspaceConfig `yaml:"workspace"`
+
+	// Path is the file the config was loaded from, empty for defaults.
+	Path string `yaml:"-"`
+}
+
+type GoplsConfig struct {
+	Path string   `yaml:"path"` // gopls binary; defaults to "gopls" in PATH
+	Args []string `yaml:"args"` // Extra flags for `gopls serve`
+	// Settings are gopls settings with the same keys as editor configuration
+	// (buildFlags, env, directoryFilters, analyses, staticcheck, ...).
+	Settings map[string]interface{} `yaml:"settings"`
+	// Remote shares a gopls daemon: "auto" or an address such as
+	// "unix;/tmp/gopls.sock" or "localhost:37374".
+	Remote    string `yaml:"remote"`
+	AutoStart bool   `yaml:"auto_start"` // Start a daemon on remote if none is listening
+}
+
+type TimeoutsConfig struct {
+	Init        Duration  `yaml:"init"`         // gopls initialize (default 20s)
+	Request     Duration  `yaml:"request"`      // Each gopls request (default: none)
+	IndexWait   *Duration `yaml:"index_wait"`   // Wait for the initial workspace load (default 10s, 0: no wait)
+	Warmup      Duration  `yaml:"warmup"`       // Diagnostic pull before queries (default 2s)
+	SessionIdle Duration  `yaml:"session_idle"` // Close idle sessions of other workspaces (default 10m)
+}
+
+type LimitsConfig struct {
+	MaxReferences  int `yaml:"max_references"`   // explain_symbol default (10)
+	SourceMaxLines int `yaml:"source_max_lines"` // Source window (100)
+	SourceMaxChars int `yaml:"source_max_chars"` // Source/body truncation (2000)
+	MaxSessions    int `yaml:"max_sessions"`     // gopls sessions running at once (4)
+}
+
+type ToolsConfig struct {
+	Enabled  []string `yaml:"enabled"`  // Only register these tools
+	Disabled []string `yaml:"disabled"` // Do not register these tools
+}
+
+type WorkspaceConfig struct {
+	Root       string   `yaml:"root"`        // Relative to the config file
+	ExtraRoots []string `yaml:"extra_roots"` // Relative to the config file
+}
+
+// Duration is a time.Duration written as a string such as "30s" or "2m".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Std returns d as a time.Duration.
+func (d Duration) Std() time.Duration { return time.Duration(d) }
+
+// Find returns the config file in dir, or "" if there is none.
+func Find(dir string) string {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load reads and validates a config file. JSON files are parsed as YAML,
+// of which JSON is a subset. Unknown keys are rejected.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.Path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg.resolvePaths()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// resolvePaths makes workspace paths absolute, relative to the config file.
+func (c *Config) resolvePaths() {
+	base := filepath.Dir(c.Path)
+	abs := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(base, p)
+	}
+	c.Workspace.Root = abs(c.Workspace.Root)
+	for i, r := range c.Workspace.ExtraRoots {
+		c.Workspace.ExtraRoots[i] = abs(r)
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Gopls.Path != "" {
+		if _, err := exec.LookPath(c.Gopls.Path); err != nil {
+			errs = append(errs, fmt.Errorf("gopls.path: %w", err))
+		}
+	}
+	if c.Gopls.Remote != "" && c.Gopls.Remote != "auto" {
+		if _, _, err := gopls.ParseRemote(c.Gopls.Remote); err != nil {
+			errs = append(errs, fmt.Errorf("gopls.remote: %w", err))
+		}
+	}
+	if c.Gopls.AutoStart && (c.Gopls.Remote == "" || c.Gopls.Remote == "auto") {
+		errs = append(errs, errors.New("gopls.auto_start: needs a remote address"))
+	}
+	if _, err := c.GoplsSettings(); err != nil {
+		errs = append(errs, fmt.Errorf("gopls.settings: %w", err))
+	}
+	var indexWait Duration // Unset: the default
+	if c.Timeouts.IndexWait != nil {
+		indexWait = *c.Timeouts.IndexWait
+	}
+	durations := []struct {
+		name string
+		d    Duration
+	}{
+		{"init", c.Timeouts.Init},
+		{"request", c.Timeouts.Request},
+		{"index_wait", indexWait},
+		{"warmup", c.Timeouts.Warmup},
+		{"session_idle", c.Timeouts.SessionIdle},
+	}
+	for _, t := range durations {
+		if t.d < 0 {
+			errs = append(errs, fmt.Errorf("timeouts.%s: must not be negative", t.name))
+		}
+	}
+	limits := []struct {
+		name string
+		v    int
+	}{
+		{"max_references", c.Limits.MaxReferences},
+		{"source_max_lines", c.Limits.SourceMaxLines},
+		{"source_max_chars", c.Limits.SourceMaxChars},
+		{"max_sessions", c.Limits.MaxSessions},
+	}
+	for _, l := range limits {
+		if l.v < 0 {
+			errs = append(errs, fmt.Errorf("limits.%s: must not be negative", l.name))
+		}
+	}
+	if len(c.Tools.Enabled) > 0 && len(c.Tools.Disabled) > 0 {
+		errs = append(errs, errors.New("tools: set either enabled or disabled, not both"))
+	}
+	dirs := append([]string{c.Workspace.Root}, c.Workspace.ExtraRoots...)
+	for i, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			field := "workspace.root"
+			if i > 0 {
+				field = fmt.Sprintf("workspace.extra_roots[%d]", i-1)
+			}
+			errs = append(errs, fmt.Errorf("%s: %s is not a directory", field, dir))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GoplsSettings converts gopls.settings; it returns nil when none are set.
+func (c *Config) GoplsSettings() (*gopls.Settings, error) {
+	if len(c.Gopls.Settings) == 0 {
+		return nil, nil
+	}
+	return gopls.SettingsFromMap(c.Gopls.Settings)
+}
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -19,18 +18,38 @@ import (
 
 type Config struct {
 	GoplsPath string
+	Args      []string // Extra flags for `gopls serve`
 	Workdir   string
 	RootURI   string
 	Timeout   time.Duration
+	// RequestTimeout bounds requests whose context has no deadline. Zero means no limit.
+	RequestTimeout time.Duration
+	// Settings are passed to gopls as initializationOptions and
+	// workspace/configuration. May be nil.
+	Settings *Settings
+	// MaxRestarts limits how many times in a row gopls is restarted after
+	// exiting unexpectedly. Zero means the default; negative disables restarts.
+	MaxRestarts int
+	// Remote connects to a shared gopls daemon instead of a private process:
+	// "auto" runs `gopls serve -remote=auto`, which starts or joins the
+	// per-user daemon; otherwise it is the daemon address, "host:port",
+	// "unix;/path/to/socket" or "tcp;host:port" (as for gopls -remote).
+	Remote string
+	// RemoteAutoStart starts `gopls serve -listen=<Remote>` when nothing is
+	// listening on an explicit Remote address yet.
+	RemoteAutoStart bool
+	// Dial, when set, replaces gopls with another transport speaking LSP
+	// (e.g. a Replayer). It is called again on every restart.
+	Dial func() (io.ReadWriteCloser, error)
+	// Recorder, when set, receives every JSON-RPC frame exchanged with gopls.
+	Recorder *Recorder
 }
 
 type Client struct {
-	cmd    *exec.Cmd
-	stdin  io.WriteCloser
-	stdout io.ReadCloser
-	reader *bufio.Reader
-	writer *bufio.Writer
+	cfg *Config
 
+	// proc is the running gopls process; it is replaced on restart.
+	proc    *process
 	writeMu sync.Mutex
 
 	nextID  uint64
@@ -39,6 +58,17 @@ type Client struct {
 
 	notifyMu sync.RWMutex
 	notify   map[string][]func(json.RawMessage)
+	handlers map[string]RequestHandler
+	progress *progressTracker
+
+	// State used by the supervisor (see supervisor.go).
+	stateMu      sync.Mutex
+	ready        chan struct{} // Closed while gopls is usable
+	deadErr      error         // Set once restarts are exhausted
+	initParams   map[string]interface{}
+	restartHooks []func(context.Context)
+	restarts     int
+	exited       chan *process
 
 	closed chan struct{}
 }
@@ -50,42 +80,29 @@ func NewClient(cfg *Config) (*Client, error) {
 	if cfg.Timeout == 0 {
 		cfg.Timeout = 30 * time.Second
 	}
-	goplsPath := cfg.GoplsPath
-	if goplsPath == "" {
-		goplsPath = "gopls"
-	}
-
-	cmd := exec.Command(goplsPath, "serve")
-	if cfg.Workdir != "" {
-		cmd.Dir = cfg.Workdir
-	}
-	cmd.Stderr = os.Stderr
-
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return nil, fmt.Errorf("gopls stdin: %w", err)
-	}
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("gopls stdout: %w", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("start gopls: %w", err)
+	if cfg.MaxRestarts == 0 {
+		cfg.MaxRestarts = defaultMaxRestarts
 	}
 
+	ready := make(chan struct{})
+	close(ready)
 	client := &Client{
-		cmd:     cmd,
-		stdin:   stdin,
-		stdout:  stdout,
-		reader:  bufio.NewReader(stdout),
-		writer:  bufio.NewWriter(stdin),
-		pending: make(map[uint64]chan *Message),
-		notify:  make(map[string][]func(json.RawMessage)),
-		closed:  make(chan struct{}),
+		cfg:      cfg,
+		pending:  make(map[uint64]chan *Message),
+		notify:   make(map[string][]func(json.RawMessage)),
+		handlers: make(map[string]RequestHandler),
+		progress: newProgressTracker(),
+		ready:    ready,
+		exited:   make(chan *process, 1),
+		closed:   make(chan struct{}),
+	}
+	client.registerDefaultHandlers()
+	client.OnNotification("$/progress", client.progress.handle)
+	if err := client.start(); err != nil {
+		return nil, err
 	}
 
-	go client.readLoop()
+	go client.supervise()
 	return client, nil
 }
 
@@ -100,35 +117,23 @@ func (c *Client) Close() error {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
-	_, _ = c.SendRequest(ctx, "shutdown", map[string]interface{}{})
-	_ = c.SendNotification("exit", map[string]interface{}{})
-
-	if c.stdin != nil {
-		_ = c.stdin.Close()
-	}
-	if c.stdout != nil {
-		_ = c.stdout.Close()
-	}
-	if c.cmd != nil {
-		_ = c.cmd.Wait()
+	_, _ = c.request(ctx, "shutdown", map[string]interface{}{})
+	if !c.isRemote() {
+		// A shared daemon outlives this client; closing the connection
+		// ends our session.
+		_ = c.notification("exit", map[string]interface{}{})
 	}
+
+	c.currentProcess().stop()
 	return nil
 }
 
 func (c *Client) Initialize(ctx context.Context, rootURI string, workspaceFolders []string) error {
 	params := map[string]interface{}{
-		"processId": os.Getpid(),
-		"rootUri":   rootURI,
-		"workspaceFolders": func() []map[string]interface{} {
-			folders := make([]map[string]interface{}, 0, len(workspaceFolders))
-			for _, f := range workspaceFolders {
-				folders = append(folders, map[string]interface{}{
-					"uri":  f,
-					"name": filepath.Base(f),
-				})
-			}
-			return folders
-		}(),
+		"processId":             os.Getpid(),
+		"rootUri":               rootURI,
+		"initializationOptions": c.cfg.Settings.Map(),
+		"workspaceFolders":      workspaceFolderList(workspaceFolders),
 		"capabilities": map[string]interface{}{
 			"textDocument": map[string]interface{}{
 				"diagnostic": map[string]interface{}{
@@ -136,25 +141,108 @@ func (c *Client) Initialize(ctx context.Context, rootURI string, workspaceFolder
 					"relatedDocumentSupport":   true,
 					"multipleLanguagesSupport": false,
 				},
-				"hover":      map[string]interface{}{"dynamicRegistration": false},
-				"definition": map[string]interface{}{"dynamicRegistration": false},
-				"references": map[string]interface{}{"dynamicRegistration": false},
+				"hover":          map[string]interface{}{"dynamicRegistration": false},
+				"definition":     map[string]interface{}{"dynamicRegistration": false},
+				"references":     map[string]interface{}{"dynamicRegistration": false},
+				"implementation": map[string]interface{}{"dynamicRegistration": false},
+				"callHierarchy":  map[string]interface{}{"dynamicRegistration": false},
+				"typeHierarchy":  map[string]interface{}{"dynamicRegistration": false},
+				"documentSymbol": map[string]interface{}{
+					"dynamicRegistration":               false,
+					"hierarchicalDocumentSymbolSupport": true,
+				},
+				"rename": map[string]interface{}{
+					"dynamicRegistration": false,
+					"prepareSupport":      true,
+				},
+			},
+			"window": map[string]interface{}{
+				"workDoneProgress": true,
 			},
 			"workspace": map[string]interface{}{
 				"workspaceFolders": true,
+				"configuration":    true,
+				"applyEdit":        false, // workspace/applyEdit is always refused
 				"symbol":           map[string]interface{}{"dynamicRegistration": false},
+				"workspaceEdit":    map[string]interface{}{"documentChanges": true},
 			},
 		},
 	}
 
+	c.stateMu.Lock()
+	c.initParams = params
+	c.stateMu.Unlock()
+	c.progress.reset()
+
 	_, err := c.SendRequest(ctx, "initialize", params)
 	if err != nil {
 		return err
 	}
-	return c.SendNotification("initialized", map[string]interface{}{})
+	if err := c.SendNotification("initialized", map[string]interface{}{}); err != nil {
+		return err
+	}
+	c.progress.armGracePeriod()
+	return nil
+}
+
+// ChangeWorkspaceFolders adds and removes workspace folders (URIs) in a
+// running gopls. The change is kept for re-initialization after a restart.
+func (c *Client) ChangeWorkspaceFolders(added, removed []string) error {
+	c.stateMu.Lock()
+	if c.initParams != nil {
+		drop := make(map[string]bool, len(removed))
+		for _, uri := range removed {
+			drop[uri] = true
+		}
+		var uris []string
+		for _, f := range c.initParams["workspaceFolders"].([]map[string]interface{}) {
+			if uri := f["uri"].(string); !drop[uri] {
+				uris = append(uris, uri)
+			}
+		}
+		params := make(map[string]interface{}, len(c.initParams))
+		for k, v := range c.initParams {
+			params[k] = v
+		}
+		params["workspaceFolders"] = workspaceFolderList(append(uris, added...))
+		c.initParams = params
+	}
+	c.stateMu.Unlock()
+
+	return c.SendNotification("workspace/didChangeWorkspaceFolders", map[string]interface{}{
+		"event": map[string]interface{}{
+			"added":   workspaceFolderList(added),
+			"removed": workspaceFolderList(removed),
+		},
+	})
+}
+
+func workspaceFolderList(uris []string) []map[string]interface{} {
+	folders := make([]map[string]interface{}, 0, len(uris))
+	for _, uri := range uris {
+		folders = append(folders, map[string]interface{}{
+			"uri":  uri,
+			"name": filepath.Base(uri),
+		})
+	}
+	return folders
 }
 
+// SendRequest sends a request to gopls and waits for its response.
+// While gopls is being restarted, it waits for the restart to finish.
 func (c *Client) SendRequest(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	if err := c.waitReady(ctx); err != nil {
+		return nil, err
+	}
+	if _, ok := ctx.Deadline(); !ok && c.cfg.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.RequestTimeout)
+		defer cancel()
+	}
+	return c.request(ctx, method, params)
+}
+
+func (c *Client) request(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
 	id := atomic.AddUint64(&c.nextID, 1)
 	respCh := make(chan *Message, 1)
 
@@ -187,13 +275,25 @@ func (c *Client) SendRequest(ctx context.Context, method string, params interfac
 		return resp.Result, nil
 	case <-ctx.Done():
 		c.pendMu.Lock()
+		_, waiting := c.pending[id]
 		delete(c.pending, id)
 		c.pendMu.Unlock()
+		if waiting {
+			// Let gopls stop computing a result nobody will read.
+			_ = c.notification("$/cancelRequest", map[string]interface{}{"id": id})
+		}
 		return nil, ctx.Err()
 	}
 }
 
 func (c *Client) SendNotification(method string, params interface{}) error {
+	if err := c.waitReady(context.Background()); err != nil {
+		return err
+	}
+	return c.notification(method, params)
+}
+
+func (c *Client) notification(method string, params interface{}) error {
 	msg := map[string]interface{}{
 		"jsonrpc": "2.0",
 		"method":  method,
@@ -215,26 +315,36 @@ func (c *Client) writeMessage(msg interface{}) error {
 	}
 	c.writeMu.Lock()
 	defer c.writeMu.Unlock()
-	if _, err := fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+	c.cfg.Recorder.record(DirClient, data)
+	writer := c.proc.writer
+	if err := WriteFrame(writer, data); err != nil {
 		return err
 	}
-	if _, err := c.writer.Write(data); err != nil {
-		return err
-	}
-	return c.writer.Flush()
+	return writer.Flush()
 }
 
-func (c *Client) readLoop() {
+// readLoop dispatches messages from gopls until the stream fails.
+func (c *Client) readLoop(reader *bufio.Reader) error {
 	for {
-		msg, err := c.readMessage()
+		data, err := ReadFrame(reader)
 		if err != nil {
-			c.closePending(err)
-			return
+			return err
+		}
+		c.cfg.Recorder.record(DirServer, data)
+		var msg *Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return err
 		}
 		if msg.Method != "" && len(msg.ID) == 0 {
 			c.dispatchNotification(msg.Method, msg.Params)
 			continue
 		}
+		if msg.Method != "" {
+			// Handle server requests off the read loop: handlers may issue
+			// requests of their own.
+			go c.dispatchRequest(msg)
+			continue
+		}
 		if len(msg.ID) > 0 {
 			id, ok := parseID(msg.ID)
 			if !ok {
@@ -269,10 +379,11 @@ func (c *Client) dispatchNotification(method string, params json.RawMessage) {
 	}
 }
 
-func (c *Client) readMessage() (*Message, error) {
+// ReadFrame reads the body of one Content-Length framed message.
+func ReadFrame(reader *bufio.Reader) ([]byte, error) {
 	contentLength := 0
 	for {
-		line, err := c.reader.ReadString('\n')
+		line, err := reader.ReadString('\n')
 		if err != nil {
 			return nil, err
 		}
@@ -294,14 +405,19 @@ func (c *Client) readMessage() (*Message, error) {
 		return nil, errors.New("missing content-length")
 	}
 	buf := make([]byte, contentLength)
-	if _, err := io.ReadFull(c.reader, buf); err != nil {
+	if _, err := io.ReadFull(reader, buf); err != nil {
 		return nil, err
 	}
-	var msg Message
-	if err := json.Unmarshal(buf, &msg); err != nil {
-		return nil, err
+	return buf, nil
+}
+
+// WriteFrame writes data with a Content-Length header.
+func WriteFrame(w io.Writer, data []byte) error {
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
 	}
-	return &msg, nil
+	_, err := w.Write(data)
+	return err
 }
 
 func parseID(raw json.RawMessage) (uint64, bool) {
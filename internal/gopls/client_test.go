@@ -0,0 +1,260 @@
+package gopls_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreamcats/bytelsp/internal/gopls"
+	"github.com/dreamcats/bytelsp/internal/gopls/goplstest"
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+// newFakeClient connects a Client to f and initializes it.
+func newFakeClient(t *testing.T, f *goplstest.Server, cfg gopls.Config) *gopls.Client {
+	t.Helper()
+	cfg.Dial = f.Dial
+	c, err := gopls.NewClient(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	if err := c.Initialize(context.Background(), "file:///work", nil); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// waitFor polls cond until it holds or a few seconds have passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	f := goplstest.NewServer()
+	f.Respond("textDocument/hover", nil)
+	f.Delay("textDocument/hover", time.Minute)
+	c := newFakeClient(t, f, gopls.Config{RequestTimeout: 100 * time.Millisecond})
+
+	start := time.Now()
+	_, err := c.SendRequest(context.Background(), "textDocument/hover", map[string]any{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("request took %v, want about the request timeout", elapsed)
+	}
+	waitFor(t, "$/cancelRequest", func() bool { return len(f.Received("$/cancelRequest")) == 1 })
+}
+
+func TestDisconnectFailsPending(t *testing.T) {
+	f := goplstest.NewServer()
+	f.Respond("textDocument/hover", nil)
+	f.Delay("textDocument/hover", time.Minute)
+	c := newFakeClient(t, f, gopls.Config{})
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.SendRequest(context.Background(), "textDocument/hover", map[string]any{})
+		errc <- err
+	}()
+	waitFor(t, "hover request", func() bool { return len(f.Received("textDocument/hover")) == 1 })
+	f.Disconnect()
+
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Fatal("pending request succeeded after disconnect")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending request still waiting after disconnect")
+	}
+
+	// The client reconnects and replays initialize.
+	f.Delay("textDocument/hover", 0)
+	if _, err := c.SendRequest(context.Background(), "textDocument/hover", map[string]any{}); err != nil {
+		t.Fatalf("request after restart: %v", err)
+	}
+	if got := c.Restarts(); got != 1 {
+		t.Errorf("restarts = %d, want 1", got)
+	}
+	if got := len(f.Received("initialize")); got != 2 {
+		t.Errorf("initialize sent %d times, want 2", got)
+	}
+}
+
+func TestMalformedFrame(t *testing.T) {
+	for name, data := range map[string]string{
+		"bad header": "Content-Length: nope\r\n\r\n",
+		"bad body":   "Content-Length: 9\r\n\r\nnot json!",
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := goplstest.NewServer()
+			f.Respond("textDocument/hover", map[string]any{"contents": "ok"})
+			c := newFakeClient(t, f, gopls.Config{})
+
+			if err := f.SendRaw([]byte(data)); err != nil {
+				t.Fatal(err)
+			}
+			waitFor(t, "restart", func() bool { return c.Restarts() == 1 })
+			if _, err := c.SendRequest(context.Background(), "textDocument/hover", map[string]any{}); err != nil {
+				t.Fatalf("request after restart: %v", err)
+			}
+		})
+	}
+}
+
+func TestDefinitionLocationLink(t *testing.T) {
+	f := goplstest.NewServer()
+	f.RespondRaw("textDocument/definition", `[{
+		"originSelectionRange": {"start": {"line": 20, "character": 15}, "end": {"line": 20, "character": 20}},
+		"targetUri": "file:///work/main.go",
+		"targetRange": {"start": {"line": 9, "character": 0}, "end": {"line": 12, "character": 1}},
+		"targetSelectionRange": {"start": {"line": 10, "character": 18}, "end": {"line": 10, "character": 23}}
+	}]`)
+	c := newFakeClient(t, f, gopls.Config{})
+
+	raw, err := c.SendRequest(context.Background(), "textDocument/definition", map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	locs, err := tools.ParseLocations(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := tools.Location{FilePath: "/work/main.go", Line: 11, Col: 19, EndLine: 11, EndCol: 24}
+	if len(locs) != 1 || locs[0] != want {
+		t.Errorf("locations = %+v, want [%+v]", locs, want)
+	}
+}
+
+func TestConfigurationRequest(t *testing.T) {
+	f := goplstest.NewServer()
+	settings := &gopls.Settings{BuildFlags: []string{"-tags=integration"}}
+	newFakeClient(t, f, gopls.Config{Settings: settings})
+
+	raw, err := f.Request(context.Background(), "workspace/configuration", map[string]any{
+		"items": []map[string]any{{"section": "gopls"}, {"section": "other"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1] != nil {
+		t.Fatalf("configuration = %s, want gopls settings and null", raw)
+	}
+	if flags, _ := got[0]["buildFlags"].([]any); len(flags) != 1 || flags[0] != "-tags=integration" {
+		t.Errorf("gopls section = %v", got[0])
+	}
+}
+
+func TestServerNotification(t *testing.T) {
+	f := goplstest.NewServer()
+	c := newFakeClient(t, f, gopls.Config{})
+	got := make(chan string, 1)
+	c.OnNotification("window/logMessage", func(raw json.RawMessage) {
+		var params struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(raw, &params)
+		got <- params.Message
+	})
+
+	if err := f.Notify("window/logMessage", map[string]any{"type": 3, "message": "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-got:
+		if msg != "hello" {
+			t.Errorf("message = %q, want hello", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification not delivered")
+	}
+}
+
+func TestChangeWorkspaceFolders(t *testing.T) {
+	f := goplstest.NewServer()
+	c, err := gopls.NewClient(&gopls.Config{Dial: f.Dial})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	ctx := context.Background()
+	if err := c.Initialize(ctx, "file:///work", []string{"file:///work", "file:///a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.ChangeWorkspaceFolders([]string{"file:///b"}, []string{"file:///a"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "didChangeWorkspaceFolders", func() bool {
+		return len(f.Received("workspace/didChangeWorkspaceFolders")) == 1
+	})
+	var change struct {
+		Event struct {
+			Added   []folder `json:"added"`
+			Removed []folder `json:"removed"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(f.Received("workspace/didChangeWorkspaceFolders")[0], &change); err != nil {
+		t.Fatal(err)
+	}
+	if got := folderURIs(change.Event.Added); got != "file:///b" {
+		t.Errorf("added = %s, want file:///b", got)
+	}
+	if got := folderURIs(change.Event.Removed); got != "file:///a" {
+		t.Errorf("removed = %s, want file:///a", got)
+	}
+
+	// gopls asking for the folders gets the current ones.
+	raw, err := f.Request(ctx, "workspace/workspaceFolders", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var folders []folder
+	if err := json.Unmarshal(raw, &folders); err != nil {
+		t.Fatal(err)
+	}
+	if got := folderURIs(folders); got != "file:///work file:///b" {
+		t.Errorf("workspaceFolders = %s, want file:///work file:///b", got)
+	}
+
+	// So does a restarted gopls.
+	f.Disconnect()
+	waitFor(t, "re-initialize", func() bool { return len(f.Received("initialize")) == 2 })
+	var init struct {
+		WorkspaceFolders []folder `json:"workspaceFolders"`
+	}
+	if err := json.Unmarshal(f.Received("initialize")[1], &init); err != nil {
+		t.Fatal(err)
+	}
+	if got := folderURIs(init.WorkspaceFolders); got != "file:///work file:///b" {
+		t.Errorf("folders after restart = %s, want file:///work file:///b", got)
+	}
+}
+
+type folder struct {
+	URI string `json:"uri"`
+}
+
+func folderURIs(folders []folder) string {
+	uris := make([]string, len(folders))
+	for i, f := range folders {
+		uris[i] = f.URI
+	}
+	return strings.Join(uris, " ")
+}
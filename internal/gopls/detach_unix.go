@@ -0,0 +1,14 @@
+//go:build !windows
+
+package gopls
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach runs cmd in its own session so the daemon survives us and does not
+// receive our terminal's signals.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -2,55 +2,355 @@ package gopls
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"os"
 	"sync"
+	"time"
 )
 
+// defaultMaxOpen is how many documents stay open in gopls before the least
+// recently used one is closed.
+const defaultMaxOpen = 64
+
+// ErrInUse is returned when a document would change under a call in
+// progress that pinned it (see PinDocuments).
+var ErrInUse = errors.New("document is in use by another call")
+
+// DocumentManager tracks the documents open in gopls. Documents opened from
+// a file on disk are re-read when the file changes, and closed again when
+// too many are open, so gopls never works from a stale copy.
 type DocumentManager struct {
-	client *Client
-	mu     sync.Mutex
-	docs   map[string]int
+	client  *Client
+	maxOpen int
+	mu      sync.Mutex
+	docs    map[string]*document
+	clock   uint64 // Incremented on each use, for LRU order
+
+	// closed holds the last version of closed documents, so that a reopened
+	// document continues from it: results gopls published for the earlier
+	// content must not match the version of the new content.
+	closed map[string]int
+}
+
+type document struct {
+	languageID string
+	version    int
+	content    string // Last content sent, re-opened after a gopls restart
+	hash       [sha256.Size]byte
+	used       uint64
+	pins       int  // Calls in progress using the document; never evicted while > 0
+	closing    bool // Close was called while pinned: close once the last pin is released
+
+	// path is the file the content was read from, empty for unsaved
+	// buffers; modTime is its modification time when it was read.
+	path    string
+	modTime time.Time
+}
+
+type pinsKey struct{}
+
+// pinSet collects the documents opened under one context.
+type pinSet struct {
+	mu   sync.Mutex
+	docs []pinnedDoc
+}
+
+type pinnedDoc struct {
+	dm  *DocumentManager
+	uri string
+	doc *document
+}
+
+// PinDocuments returns a context under which every document opened (or
+// updated) stays open until release is called, instead of being evicted
+// while it is still queried. Use one per tool call.
+func PinDocuments(ctx context.Context) (context.Context, func()) {
+	set := &pinSet{}
+	release := func() {
+		set.mu.Lock()
+		docs := set.docs
+		set.docs = nil
+		set.mu.Unlock()
+		for _, p := range docs {
+			p.dm.unpin(p.uri, p.doc)
+		}
+	}
+	return context.WithValue(ctx, pinsKey{}, set), release
+}
+
+// pin pins doc for the calls of ctx, if it has a pin set. dm.mu is held.
+func (dm *DocumentManager) pin(ctx context.Context, uri string, doc *document) {
+	set, _ := ctx.Value(pinsKey{}).(*pinSet)
+	if set == nil {
+		return
+	}
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	for _, p := range set.docs {
+		if p.doc == doc {
+			return
+		}
+	}
+	doc.pins++
+	set.docs = append(set.docs, pinnedDoc{dm: dm, uri: uri, doc: doc})
+}
+
+// unpin releases one pin of doc, closing it if a Close was deferred.
+func (dm *DocumentManager) unpin(uri string, doc *document) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	doc.pins--
+	if doc.pins == 0 && doc.closing && dm.docs[uri] == doc {
+		_ = dm.close(uri)
+	}
+}
+
+// pinnedByOthers reports whether calls other than the one of ctx pinned doc.
+// dm.mu is held.
+func pinnedByOthers(ctx context.Context, doc *document) bool {
+	pins := doc.pins
+	if set, _ := ctx.Value(pinsKey{}).(*pinSet); set != nil && set.has(doc) {
+		pins--
+	}
+	return pins > 0
+}
+
+func (set *pinSet) has(doc *document) bool {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	for _, p := range set.docs {
+		if p.doc == doc {
+			return true
+		}
+	}
+	return false
 }
 
 func NewDocumentManager(client *Client) *DocumentManager {
-	return &DocumentManager{client: client, docs: make(map[string]int)}
+	dm := &DocumentManager{client: client, maxOpen: defaultMaxOpen, docs: make(map[string]*document), closed: make(map[string]int)}
+	client.onRestart(dm.reopen)
+	return dm
 }
 
+// OpenOrUpdate opens uri with content as an unsaved buffer, or updates it if
+// it is open, and returns the document version. Content equal to what gopls
+// already has is not sent again. It fails with ErrInUse if other content is
+// open and pinned by another call.
 func (dm *DocumentManager) OpenOrUpdate(ctx context.Context, uri, languageID, content string) (int, error) {
+	return dm.open(ctx, uri, languageID, content, "", time.Time{})
+}
+
+// OpenFile opens uri with the content of the file at path, or brings it up
+// to date if it is open. Later changes to the file are picked up by Resync.
+func (dm *DocumentManager) OpenFile(ctx context.Context, uri, path string) (string, int, error) {
+	// Stat before reading: a change in between is then seen by Resync.
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", 0, err
+	}
+	version, err := dm.open(ctx, uri, "go", string(data), path, info.ModTime())
+	return string(data), version, err
+}
+
+func (dm *DocumentManager) open(ctx context.Context, uri, languageID, content, path string, modTime time.Time) (int, error) {
+	// Wait for a restart to finish outside the lock: reopen needs it.
+	if err := dm.client.waitReady(ctx); err != nil {
+		return 0, err
+	}
 	dm.mu.Lock()
 	defer dm.mu.Unlock()
 
-	version, exists := dm.docs[uri]
+	dm.clock++
+	hash := sha256.Sum256([]byte(content))
+	doc, exists := dm.docs[uri]
 	if !exists {
-		version = 1
+		dm.evict()
+		doc = &document{languageID: languageID, version: dm.closed[uri] + 1, content: content, hash: hash, used: dm.clock, path: path, modTime: modTime}
 		params := map[string]interface{}{
 			"textDocument": map[string]interface{}{
 				"uri":        uri,
 				"languageId": languageID,
-				"version":    version,
+				"version":    doc.version,
 				"text":       content,
 			},
 		}
-		if err := dm.client.SendNotification("textDocument/didOpen", params); err != nil {
+		if err := dm.client.notification("textDocument/didOpen", params); err != nil {
 			return 0, fmt.Errorf("didOpen: %w", err)
 		}
-		dm.docs[uri] = version
-		return version, nil
+		dm.docs[uri] = doc
+		delete(dm.closed, uri)
+		dm.pin(ctx, uri, doc)
+		return doc.version, nil
 	}
 
-	version++
+	if doc.hash != hash && pinnedByOthers(ctx, doc) {
+		// The other call computed positions from the current content.
+		return 0, ErrInUse
+	}
+	dm.pin(ctx, uri, doc)
+	doc.closing = false
+	doc.used = dm.clock
+	doc.path, doc.modTime = path, modTime
+	if doc.hash == hash {
+		return doc.version, nil
+	}
+	if err := dm.change(uri, doc, content, hash); err != nil {
+		return 0, err
+	}
+	return doc.version, nil
+}
+
+// change sends content as the new full text of an open document.
+func (dm *DocumentManager) change(uri string, doc *document, content string, hash [sha256.Size]byte) error {
+	doc.version++
+	doc.content = content
+	doc.hash = hash
 	params := map[string]interface{}{
 		"textDocument": map[string]interface{}{
 			"uri":     uri,
-			"version": version,
+			"version": doc.version,
 		},
 		"contentChanges": []map[string]interface{}{{
 			"text": content,
 		}},
 	}
-	if err := dm.client.SendNotification("textDocument/didChange", params); err != nil {
-		return 0, fmt.Errorf("didChange: %w", err)
+	if err := dm.client.notification("textDocument/didChange", params); err != nil {
+		return fmt.Errorf("didChange: %w", err)
+	}
+	return nil
+}
+
+// UpdateIfOpen sends the new content of uri to gopls if the document is open.
+// It reports whether the document was open.
+func (dm *DocumentManager) UpdateIfOpen(ctx context.Context, uri, content string) (bool, error) {
+	dm.mu.Lock()
+	doc, exists := dm.docs[uri]
+	var path string
+	if exists {
+		path = doc.path
+	}
+	dm.mu.Unlock()
+	if !exists {
+		return false, nil
+	}
+	if path != "" {
+		// Written to disk by the caller: keep following the file.
+		_, _, err := dm.OpenFile(ctx, uri, path)
+		return true, err
+	}
+	_, err := dm.OpenOrUpdate(ctx, uri, "go", content)
+	return true, err
+}
+
+// Close sends didClose for uri if it is open, so gopls drops the buffer
+// and goes back to the file on disk (or forgets the file if there is none).
+// A document pinned by calls in progress is closed when they release it.
+func (dm *DocumentManager) Close(ctx context.Context, uri string) error {
+	if err := dm.client.waitReady(ctx); err != nil {
+		return err
+	}
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	doc, exists := dm.docs[uri]
+	if !exists {
+		return nil
+	}
+	if doc.pins > 0 {
+		doc.closing = true
+		return nil
+	}
+	return dm.close(uri)
+}
+
+func (dm *DocumentManager) close(uri string) error {
+	dm.closed[uri] = dm.docs[uri].version
+	delete(dm.docs, uri)
+	params := map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+	}
+	if err := dm.client.notification("textDocument/didClose", params); err != nil {
+		return fmt.Errorf("didClose: %w", err)
+	}
+	return nil
+}
+
+// evict closes least recently used documents until there is room for one
+// more. Pinned documents are kept, even if that leaves too many open.
+func (dm *DocumentManager) evict() {
+	for len(dm.docs) >= dm.maxOpen {
+		var oldest string
+		for uri, doc := range dm.docs {
+			if doc.pins > 0 {
+				continue
+			}
+			if oldest == "" || doc.used < dm.docs[oldest].used {
+				oldest = uri
+			}
+		}
+		if oldest == "" {
+			return
+		}
+		_ = dm.close(oldest)
+	}
+}
+
+// Resync brings documents opened from disk up to date with their files:
+// changed files are sent again and deleted ones are closed. Only files
+// whose modification time changed are read.
+func (dm *DocumentManager) Resync(ctx context.Context) error {
+	if err := dm.client.waitReady(ctx); err != nil {
+		return err
+	}
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	var errs []error
+	for uri, doc := range dm.docs {
+		if doc.path == "" {
+			continue
+		}
+		info, err := os.Stat(doc.path)
+		if err != nil {
+			errs = append(errs, dm.close(uri))
+			continue
+		}
+		if info.ModTime().Equal(doc.modTime) {
+			continue
+		}
+		data, err := os.ReadFile(doc.path)
+		if err != nil {
+			errs = append(errs, dm.close(uri))
+			continue
+		}
+		doc.modTime = info.ModTime()
+		if hash := sha256.Sum256(data); hash != doc.hash {
+			errs = append(errs, dm.change(uri, doc, string(data), hash))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reopen sends didOpen for every tracked document to a restarted gopls.
+func (dm *DocumentManager) reopen(ctx context.Context) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	for uri, doc := range dm.docs {
+		if ctx.Err() != nil {
+			return
+		}
+		params := map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"uri":        uri,
+				"languageId": doc.languageID,
+				"version":    doc.version,
+				"text":       doc.content,
+			},
+		}
+		_ = dm.client.notification("textDocument/didOpen", params)
 	}
-	dm.docs[uri] = version
-	return version, nil
 }
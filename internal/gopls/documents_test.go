@@ -0,0 +1,218 @@
+package gopls_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreamcats/bytelsp/internal/gopls"
+	"github.com/dreamcats/bytelsp/internal/gopls/goplstest"
+)
+
+func TestEvictSkipsPinned(t *testing.T) {
+	f := goplstest.NewServer()
+	c := newFakeClient(t, f, gopls.Config{})
+	dm := gopls.NewDocumentManager(c)
+	dm.SetMaxOpen(2)
+	ctx := context.Background()
+
+	pinned, unpin := gopls.PinDocuments(ctx)
+	for _, uri := range []string{"file:///a.go", "file:///b.go", "file:///c.go"} {
+		docCtx := ctx
+		if uri == "file:///a.go" {
+			docCtx = pinned
+		}
+		if _, err := dm.OpenOrUpdate(docCtx, uri, "go", "package p"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, "didClose", func() bool { return len(f.Received("textDocument/didClose")) == 1 })
+	if got := closedURI(t, f, 0); got != "file:///b.go" {
+		t.Errorf("evicted %s while a.go was pinned, want b.go", got)
+	}
+
+	unpin()
+	if _, err := dm.OpenOrUpdate(ctx, "file:///d.go", "go", "package p"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "didClose", func() bool { return len(f.Received("textDocument/didClose")) == 2 })
+	if got := closedURI(t, f, 1); got != "file:///a.go" {
+		t.Errorf("evicted %s after unpinning, want a.go", got)
+	}
+}
+
+func TestOpenSkipsUnchangedContent(t *testing.T) {
+	f := goplstest.NewServer()
+	c := newFakeClient(t, f, gopls.Config{})
+	dm := gopls.NewDocumentManager(c)
+	ctx := context.Background()
+
+	for _, content := range []string{"package p", "package p", "package q"} {
+		if _, err := dm.OpenOrUpdate(ctx, "file:///a.go", "go", content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, "didChange", func() bool { return len(f.Received("textDocument/didChange")) == 1 })
+	if got := len(f.Received("textDocument/didOpen")); got != 1 {
+		t.Errorf("didOpen sent %d times, want 1", got)
+	}
+}
+
+// closedURI returns the URI of the i-th didClose f received.
+func closedURI(t *testing.T, f *goplstest.Server, i int) string {
+	t.Helper()
+	var params struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+	}
+	if err := json.Unmarshal(f.Received("textDocument/didClose")[i], &params); err != nil {
+		t.Fatal(err)
+	}
+	return params.TextDocument.URI
+}
+
+func TestOverlayRefusedWhilePinned(t *testing.T) {
+	f := goplstest.NewServer()
+	c := newFakeClient(t, f, gopls.Config{})
+	dm := gopls.NewDocumentManager(c)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "a.go")
+	if err := os.WriteFile(path, []byte("package p\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	uri := "file://" + path
+
+	reader, unpinReader := gopls.PinDocuments(ctx)
+	if _, _, err := dm.OpenFile(reader, uri, path); err != nil {
+		t.Fatal(err)
+	}
+	overlay, unpinOverlay := gopls.PinDocuments(ctx)
+	defer unpinOverlay()
+	if _, err := dm.OpenOrUpdate(overlay, uri, "go", "package p\n\nvar x int\n"); !errors.Is(err, gopls.ErrInUse) {
+		t.Fatalf("overlay on a pinned file: err = %v, want ErrInUse", err)
+	}
+	// The reader may open its own document again.
+	if _, _, err := dm.OpenFile(reader, uri, path); err != nil {
+		t.Fatal(err)
+	}
+
+	unpinReader()
+	if _, err := dm.OpenOrUpdate(overlay, uri, "go", "package p\n\nvar x int\n"); err != nil {
+		t.Fatalf("overlay after release: %v", err)
+	}
+	if got := len(f.Received("textDocument/didChange")); got != 1 {
+		t.Errorf("didChange sent %d times, want 1", got)
+	}
+}
+
+func TestCloseWaitsForPins(t *testing.T) {
+	f := goplstest.NewServer()
+	f.Respond("textDocument/hover", nil)
+	c := newFakeClient(t, f, gopls.Config{})
+	dm := gopls.NewDocumentManager(c)
+	ctx := context.Background()
+
+	other, unpinOther := gopls.PinDocuments(ctx)
+	if _, err := dm.OpenOrUpdate(other, "file:///a.go", "go", "package p"); err != nil {
+		t.Fatal(err)
+	}
+	own, unpinOwn := gopls.PinDocuments(ctx)
+	if _, err := dm.OpenOrUpdate(own, "file:///a.go", "go", "package p"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dm.Close(own, "file:///a.go"); err != nil {
+		t.Fatal(err)
+	}
+	unpinOwn()
+	// Messages arrive in order: a didClose would be seen before the answer
+	// to a later request.
+	if _, err := c.SendRequest(ctx, "textDocument/hover", map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(f.Received("textDocument/didClose")); got != 0 {
+		t.Fatal("didClose sent while the document was still pinned")
+	}
+
+	unpinOther()
+	waitFor(t, "didClose", func() bool { return len(f.Received("textDocument/didClose")) == 1 })
+}
+
+func TestReopenContinuesVersion(t *testing.T) {
+	f := goplstest.NewServer()
+	c := newFakeClient(t, f, gopls.Config{})
+	dm := gopls.NewDocumentManager(c)
+	dm.SetMaxOpen(1)
+	ctx := context.Background()
+
+	open := func(uri, content string) int {
+		t.Helper()
+		version, err := dm.OpenOrUpdate(ctx, uri, "go", content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return version
+	}
+	open("file:///a.go", "package p")
+	if v := open("file:///a.go", "package q"); v != 2 {
+		t.Fatalf("version after change = %d, want 2", v)
+	}
+	open("file:///b.go", "package p") // Evicts a.go
+	if v := open("file:///a.go", "package r"); v != 3 {
+		t.Errorf("version after reopen = %d, want 3", v)
+	}
+}
+
+func TestResyncFollowsFile(t *testing.T) {
+	f := goplstest.NewServer()
+	c := newFakeClient(t, f, gopls.Config{})
+	dm := gopls.NewDocumentManager(c)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "a.go")
+	uri := "file://" + path
+	write := func(content string, mtime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mtime := time.Now().Add(-time.Hour)
+	write("package p\n", mtime)
+	if _, _, err := dm.OpenFile(ctx, uri, path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Same modification time: not read again, even though it changed.
+	write("package q\n", mtime)
+	if err := dm.Resync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	write("package r\n", mtime.Add(time.Minute))
+	if err := dm.Resync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "didChange", func() bool { return len(f.Received("textDocument/didChange")) > 0 })
+	changes := f.Received("textDocument/didChange")
+	if len(changes) != 1 || !strings.Contains(string(changes[0]), `package r\n`) {
+		t.Errorf("didChange = %s, want one with the new content", changes)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := dm.Resync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "didClose", func() bool { return len(f.Received("textDocument/didClose")) == 1 })
+	if got := closedURI(t, f, 0); got != uri {
+		t.Errorf("closed %s, want %s", got, uri)
+	}
+}
@@ -0,0 +1,4 @@
+package gopls
+
+// SetMaxOpen changes how many documents stay open before eviction.
+func (dm *DocumentManager) SetMaxOpen(n int) { dm.maxOpen = n }
@@ -0,0 +1,287 @@
+// Package goplstest provides a programmable in-process language server for
+// testing code that talks to gopls without running gopls.
+package goplstest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/dreamcats/bytelsp/internal/gopls"
+)
+
+// JSON-RPC error codes of the replies to requests without a handler.
+const (
+	codeMethodNotFound = -32601
+	codeInternalError  = -32603
+)
+
+// Handler answers one request (or handles one notification) sent to a
+// Server. Returning a *gopls.ResponseError controls the error code.
+type Handler func(params json.RawMessage) (interface{}, error)
+
+// Server is a programmable in-process language server. Use its Dial method
+// as gopls.Config.Dial (or mcp.Options.GoplsDial). Requests without a
+// handler get a MethodNotFound error, except initialize and shutdown, which
+// succeed.
+type Server struct {
+	mu       sync.Mutex
+	handlers map[string]Handler
+	delays   map[string]time.Duration
+	received []gopls.Message
+	conn     *fakeConn
+
+	nextID int
+}
+
+type fakeConn struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+	closed  chan struct{}
+
+	mu      sync.Mutex
+	pending map[string]chan *gopls.Message // Server requests awaiting the client
+}
+
+func NewServer() *Server {
+	f := &Server{
+		handlers: make(map[string]Handler),
+		delays:   make(map[string]time.Duration),
+	}
+	f.Respond("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}})
+	f.Respond("shutdown", nil)
+	return f
+}
+
+// Handle sets the handler for method, replacing any previous one.
+func (f *Server) Handle(method string, h Handler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[method] = h
+}
+
+// Respond makes requests for method succeed with result.
+func (f *Server) Respond(method string, result interface{}) {
+	f.Handle(method, func(json.RawMessage) (interface{}, error) { return result, nil })
+}
+
+// RespondRaw makes requests for method succeed with a literal JSON result,
+// e.g. a captured gopls response.
+func (f *Server) RespondRaw(method, result string) {
+	f.Respond(method, json.RawMessage(result))
+}
+
+// Fail makes requests for method fail with the given error.
+func (f *Server) Fail(method string, code int, message string) {
+	f.Handle(method, func(json.RawMessage) (interface{}, error) {
+		return nil, &gopls.ResponseError{Code: code, Message: message}
+	})
+}
+
+// Delay holds back answers to method by d. A delay longer than the
+// client's timeout simulates a hung request.
+func (f *Server) Delay(method string, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delays[method] = d
+}
+
+// Dial connects a new client, replacing (and dropping) any previous one.
+func (f *Server) Dial() (io.ReadWriteCloser, error) {
+	client, server := net.Pipe()
+	fc := &fakeConn{conn: server, closed: make(chan struct{}), pending: make(map[string]chan *gopls.Message)}
+	f.mu.Lock()
+	old := f.conn
+	f.conn = fc
+	f.mu.Unlock()
+	if old != nil {
+		old.close()
+	}
+	go f.serve(fc)
+	return client, nil
+}
+
+// Disconnect drops the current connection, as if gopls had crashed.
+func (f *Server) Disconnect() {
+	if fc := f.current(); fc != nil {
+		fc.close()
+	}
+}
+
+// Received returns the params of every message the client sent for method,
+// in order.
+func (f *Server) Received(method string) []json.RawMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []json.RawMessage
+	for _, msg := range f.received {
+		if msg.Method == method {
+			out = append(out, msg.Params)
+		}
+	}
+	return out
+}
+
+// Notify sends a notification to the client.
+func (f *Server) Notify(method string, params interface{}) error {
+	return f.send(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// Request sends a server-to-client request and waits for the answer.
+func (f *Server) Request(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	fc := f.current()
+	if fc == nil {
+		return nil, errors.New("goplstest: no client connected")
+	}
+	f.mu.Lock()
+	f.nextID++
+	id := fmt.Sprintf("%q", fmt.Sprintf("fake-%d", f.nextID))
+	f.mu.Unlock()
+
+	ch := make(chan *gopls.Message, 1)
+	fc.mu.Lock()
+	fc.pending[id] = ch
+	fc.mu.Unlock()
+	defer func() {
+		fc.mu.Lock()
+		delete(fc.pending, id)
+		fc.mu.Unlock()
+	}()
+
+	msg := map[string]interface{}{"jsonrpc": "2.0", "id": json.RawMessage(id), "method": method, "params": params}
+	if err := fc.send(msg); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	case <-fc.closed:
+		return nil, errors.New("goplstest: client disconnected")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// SendRaw writes data to the client as-is, without framing, to test how
+// the client copes with malformed input such as a bad Content-Length
+// header or a body that is not JSON.
+func (f *Server) SendRaw(data []byte) error {
+	fc := f.current()
+	if fc == nil {
+		return errors.New("goplstest: no client connected")
+	}
+	fc.writeMu.Lock()
+	defer fc.writeMu.Unlock()
+	_, err := fc.conn.Write(data)
+	return err
+}
+
+func (f *Server) current() *fakeConn {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.conn
+}
+
+func (f *Server) send(msg interface{}) error {
+	fc := f.current()
+	if fc == nil {
+		return errors.New("goplstest: no client connected")
+	}
+	return fc.send(msg)
+}
+
+func (f *Server) serve(fc *fakeConn) {
+	defer fc.close()
+	reader := bufio.NewReader(fc.conn)
+	for {
+		data, err := gopls.ReadFrame(reader)
+		if err != nil {
+			return
+		}
+		var msg gopls.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		if msg.Method == "" {
+			// The client's answer to one of our requests.
+			fc.mu.Lock()
+			ch := fc.pending[string(msg.ID)]
+			fc.mu.Unlock()
+			if ch != nil {
+				ch <- &msg
+			}
+			continue
+		}
+
+		f.mu.Lock()
+		f.received = append(f.received, msg)
+		handler := f.handlers[msg.Method]
+		delay := f.delays[msg.Method]
+		f.mu.Unlock()
+		if len(msg.ID) == 0 {
+			if handler != nil {
+				go func() { _, _ = handler(msg.Params) }()
+			}
+			continue
+		}
+		go f.answer(fc, msg, handler, delay)
+	}
+}
+
+func (f *Server) answer(fc *fakeConn, msg gopls.Message, handler Handler, delay time.Duration) {
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-fc.closed:
+			return
+		}
+	}
+	reply := &gopls.Message{JSONRPC: "2.0", ID: msg.ID}
+	if handler == nil {
+		reply.Error = &gopls.ResponseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+	} else if result, err := handler(msg.Params); err != nil {
+		var respErr *gopls.ResponseError
+		if !errors.As(err, &respErr) {
+			respErr = &gopls.ResponseError{Code: codeInternalError, Message: err.Error()}
+		}
+		reply.Error = respErr
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			reply.Error = &gopls.ResponseError{Code: codeInternalError, Message: err.Error()}
+		} else {
+			reply.Result = data
+		}
+	}
+	_ = fc.send(reply)
+}
+
+func (fc *fakeConn) send(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	fc.writeMu.Lock()
+	defer fc.writeMu.Unlock()
+	return gopls.WriteFrame(fc.conn, data)
+}
+
+func (fc *fakeConn) close() {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	select {
+	case <-fc.closed:
+	default:
+		close(fc.closed)
+		_ = fc.conn.Close()
+	}
+}
@@ -1,6 +1,9 @@
 package gopls
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+)
 
 type Message struct {
 	JSONRPC string          `json:"jsonrpc"`
@@ -15,3 +18,13 @@ type ResponseError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 }
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("lsp error %d: %s", e.Code, e.Message)
+}
+
+// JSON-RPC error codes used in replies to server requests.
+const (
+	codeMethodNotFound = -32601
+	codeInternalError  = -32603
+)
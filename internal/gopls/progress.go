@@ -0,0 +1,151 @@
+package gopls
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// gopls reports its initial workspace load as work-done progress. If no
+// progress begins this long after initialization, the load is assumed done
+// (older gopls versions, or servers that do not report progress).
+const progressGracePeriod = 3 * time.Second
+
+// Progress is the state of one active work-done progress token.
+type Progress struct {
+	Token      string
+	Title      string
+	Message    string
+	Percentage int // -1 when not reported
+}
+
+// progressTracker follows $/progress notifications and signals when the
+// initial workspace load is over.
+type progressTracker struct {
+	mu     sync.Mutex
+	active map[string]*Progress
+	begun  bool
+	loaded chan struct{}
+	closed bool
+}
+
+func newProgressTracker() *progressTracker {
+	return &progressTracker{active: make(map[string]*Progress), loaded: make(chan struct{})}
+}
+
+// reset starts tracking a new initial load; call it before initialize.
+func (t *progressTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active = make(map[string]*Progress)
+	t.begun = false
+	if t.closed {
+		t.loaded = make(chan struct{})
+		t.closed = false
+	}
+}
+
+// armGracePeriod marks the load as done if no progress has begun shortly
+// after the initialized notification.
+func (t *progressTracker) armGracePeriod() {
+	t.mu.Lock()
+	loaded := t.loaded
+	t.mu.Unlock()
+	time.AfterFunc(progressGracePeriod, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.loaded == loaded && !t.begun {
+			t.markLoaded()
+		}
+	})
+}
+
+func (t *progressTracker) markLoaded() {
+	if !t.closed {
+		close(t.loaded)
+		t.closed = true
+	}
+}
+
+func (t *progressTracker) handle(raw json.RawMessage) {
+	var p struct {
+		Token json.RawMessage `json:"token"`
+		Value struct {
+			Kind       string `json:"kind"`
+			Title      string `json:"title"`
+			Message    string `json:"message"`
+			Percentage *int   `json:"percentage"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &p); err != nil || len(p.Token) == 0 {
+		return
+	}
+	token := string(p.Token)
+	percentage := -1
+	if p.Value.Percentage != nil {
+		percentage = *p.Value.Percentage
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch p.Value.Kind {
+	case "begin":
+		t.begun = true
+		t.active[token] = &Progress{Token: token, Title: p.Value.Title, Message: p.Value.Message, Percentage: percentage}
+	case "report":
+		if cur := t.active[token]; cur != nil {
+			if p.Value.Message != "" {
+				cur.Message = p.Value.Message
+			}
+			if percentage >= 0 {
+				cur.Percentage = percentage
+			}
+		}
+	case "end":
+		delete(t.active, token)
+		if t.begun && len(t.active) == 0 {
+			t.markLoaded()
+		}
+	}
+}
+
+func (t *progressTracker) snapshot() []Progress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]Progress, 0, len(t.active))
+	for _, p := range t.active {
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Token < out[j].Token })
+	return out
+}
+
+func (t *progressTracker) loadedChan() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.loaded
+}
+
+// WorkspaceLoaded returns a channel that is closed once gopls has finished
+// its initial workspace load (package loading and indexing).
+func (c *Client) WorkspaceLoaded() <-chan struct{} {
+	return c.progress.loadedChan()
+}
+
+// WaitWorkspaceLoaded blocks until the initial workspace load completes or
+// ctx is done.
+func (c *Client) WaitWorkspaceLoaded(ctx context.Context) error {
+	select {
+	case <-c.WorkspaceLoaded():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ActiveProgress returns the work gopls currently reports as in progress.
+func (c *Client) ActiveProgress() []Progress {
+	return c.progress.snapshot()
+}
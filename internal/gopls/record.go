@@ -0,0 +1,114 @@
+package gopls
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Direction of a recorded frame.
+const (
+	DirClient = "client" // Sent to gopls
+	DirServer = "server" // Received from gopls
+)
+
+// rootPlaceholder stands for the workspace root in recordings, so they can
+// be replayed from another checkout.
+const rootPlaceholder = "$ROOT"
+
+// Frame is one recorded JSON-RPC message.
+type Frame struct {
+	Dir     string          `json:"dir"`
+	Elapsed time.Duration   `json:"elapsed"` // Since the recording started
+	Message json.RawMessage `json:"message"`
+}
+
+// Recorder writes every frame exchanged with gopls as a JSON line. Occurrences
+// of the workspace root are replaced with $ROOT.
+type Recorder struct {
+	mu    sync.Mutex
+	w     io.WriteCloser
+	buf   *bufio.Writer
+	root  string
+	start time.Time
+	err   error
+}
+
+// NewRecorder creates (or truncates) the recording file at path. root is
+// the workspace root path; it may be empty.
+func NewRecorder(path, root string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create recording: %w", err)
+	}
+	return &Recorder{w: f, buf: bufio.NewWriter(f), root: root, start: time.Now()}, nil
+}
+
+// record appends one frame. Recording errors are kept for Close and do not
+// affect the session.
+func (r *Recorder) record(dir string, data []byte) {
+	if r == nil {
+		return
+	}
+	if r.root != "" {
+		data = bytes.ReplaceAll(data, []byte(r.root), []byte(rootPlaceholder))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	line, err := json.Marshal(Frame{Dir: dir, Elapsed: time.Since(r.start), Message: data})
+	if err == nil {
+		_, err = r.buf.Write(append(line, '\n'))
+	}
+	if err == nil {
+		// Flush per frame so a crash still leaves a usable recording.
+		err = r.buf.Flush()
+	}
+	r.err = err
+}
+
+// Close flushes and closes the recording, returning the first write error.
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.buf.Flush(); r.err == nil {
+		r.err = err
+	}
+	if err := r.w.Close(); r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// LoadRecording reads a recording, substituting root for $ROOT.
+func LoadRecording(path, root string) ([]Frame, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var frames []Frame
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var f Frame
+		if err := json.Unmarshal(line, &f); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		if root != "" {
+			f.Message = bytes.ReplaceAll(f.Message, []byte(rootPlaceholder), []byte(root))
+		}
+		frames = append(frames, f)
+	}
+	return frames, nil
+}
@@ -0,0 +1,106 @@
+package gopls
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	dialTimeout = 2 * time.Second
+	// How long to wait for an auto-started daemon to accept connections.
+	autoStartWait = 10 * time.Second
+	// An auto-started daemon exits after being idle this long.
+	daemonIdleTimeout = time.Minute
+)
+
+// isRemote reports whether the client talks to a daemon over a socket
+// rather than to a process it owns.
+func (c *Client) isRemote() bool {
+	return c.cfg.Remote != "" && c.cfg.Remote != "auto"
+}
+
+// dialRemote connects to the gopls daemon at c.cfg.Remote, starting one
+// first if RemoteAutoStart is set and nothing is listening.
+func (c *Client) dialRemote() (*process, net.Conn, error) {
+	network, addr, err := ParseRemote(c.cfg.Remote)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := net.DialTimeout(network, addr, dialTimeout)
+	if err != nil && c.cfg.RemoteAutoStart {
+		if startErr := c.startDaemon(); startErr != nil {
+			return nil, nil, fmt.Errorf("dial gopls at %s: %v; auto-start: %w", c.cfg.Remote, err, startErr)
+		}
+		conn, err = dialUntil(network, addr, time.Now().Add(autoStartWait))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("dial gopls at %s: %w", c.cfg.Remote, err)
+	}
+	return &process{conn: conn}, conn, nil
+}
+
+// startDaemon launches a detached `gopls serve -listen` on the remote
+// address. The daemon is shared and is not stopped by Close; it exits on
+// its own once idle.
+func (c *Client) startDaemon() error {
+	network, addr, err := ParseRemote(c.cfg.Remote)
+	if err != nil {
+		return err
+	}
+	listen := addr
+	if network == "unix" {
+		listen = "unix;" + addr
+	}
+	args := append([]string{"serve"}, c.cfg.Args...)
+	args = append(args, "-listen="+listen, fmt.Sprintf("-listen.timeout=%s", daemonIdleTimeout))
+	cmd := exec.Command(c.goplsPath(), args...)
+	if c.cfg.Workdir != "" {
+		cmd.Dir = c.cfg.Workdir
+	}
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Reap the daemon if it exits while we are still running.
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
+
+func dialUntil(network, addr string, deadline time.Time) (net.Conn, error) {
+	for {
+		conn, err := net.DialTimeout(network, addr, dialTimeout)
+		if err == nil || time.Now().After(deadline) {
+			return conn, err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// ParseRemote splits a gopls remote address into a network and address.
+// It accepts "unix;/path", "tcp;host:port", "host:port" and, as a shorthand
+// for unix sockets, absolute paths.
+func ParseRemote(remote string) (network, addr string, err error) {
+	if remote == "" || remote == "auto" {
+		return "", "", fmt.Errorf("remote %q is not a daemon address", remote)
+	}
+	if network, addr, ok := strings.Cut(remote, ";"); ok {
+		if network != "unix" && network != "tcp" {
+			return "", "", fmt.Errorf("remote %q: unsupported network %q", remote, network)
+		}
+		if addr == "" {
+			return "", "", fmt.Errorf("remote %q: missing address", remote)
+		}
+		return network, addr, nil
+	}
+	if strings.HasPrefix(remote, "/") {
+		return "unix", remote, nil
+	}
+	if _, _, err := net.SplitHostPort(remote); err != nil {
+		return "", "", errors.New("remote must be \"auto\", host:port, unix;/path or tcp;host:port")
+	}
+	return "tcp", remote, nil
+}
@@ -0,0 +1,163 @@
+package gopls
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// Replayer serves a recording in place of gopls, so the client and the
+// tools built on it can run offline and deterministically. Use its Dial
+// method as Config.Dial.
+//
+// Each request is answered with the response recorded for the first unused
+// request with the same method and parameters, or failing that the same
+// method. Messages gopls sent after a client message, up to the next client
+// message, are replayed after that message in their recorded order.
+type Replayer struct {
+	frames []replayFrame
+	// responses maps a recorded client request ID to gopls' response.
+	responses map[string]json.RawMessage
+}
+
+type replayFrame struct {
+	dir    string
+	msg    Message
+	raw    json.RawMessage
+	params string // Canonical params, for matching
+}
+
+// NewReplayer loads the recording at path, substituting root for $ROOT.
+func NewReplayer(path, root string) (*Replayer, error) {
+	frames, err := LoadRecording(path, root)
+	if err != nil {
+		return nil, err
+	}
+	r := &Replayer{responses: make(map[string]json.RawMessage)}
+	for _, f := range frames {
+		var msg Message
+		if err := json.Unmarshal(f.Message, &msg); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if f.Dir == DirServer && msg.Method == "" && len(msg.ID) > 0 {
+			r.responses[string(msg.ID)] = f.Message
+		}
+		if f.Dir == DirClient && msg.Method == "" {
+			continue // Our answers to gopls' requests
+		}
+		r.frames = append(r.frames, replayFrame{dir: f.Dir, msg: msg, raw: f.Message, params: canonicalJSON(msg.Params)})
+	}
+	return r, nil
+}
+
+// Dial starts a replay session. Every session replays from the beginning.
+func (r *Replayer) Dial() (io.ReadWriteCloser, error) {
+	client, server := net.Pipe()
+	go r.serve(server)
+	return client, nil
+}
+
+func (r *Replayer) serve(conn net.Conn) {
+	defer conn.Close()
+	var writeMu sync.Mutex
+	send := func(data []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return WriteFrame(conn, data)
+	}
+	used := make([]bool, len(r.frames))
+	reader := bufio.NewReader(conn)
+	for {
+		data, err := ReadFrame(reader)
+		if err != nil {
+			return
+		}
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Method == "" {
+			continue // Answers to replayed server requests are not checked
+		}
+		i := r.match(used, msg.Method, canonicalJSON(msg.Params))
+		if i < 0 {
+			if len(msg.ID) > 0 {
+				reply, _ := json.Marshal(&Message{JSONRPC: "2.0", ID: msg.ID, Error: &ResponseError{
+					Code:    codeMethodNotFound,
+					Message: "no recorded response for " + msg.Method,
+				}})
+				if send(reply) != nil {
+					return
+				}
+			}
+			continue
+		}
+		used[i] = true
+		recordedID := r.frames[i].msg.ID
+		answered := len(msg.ID) == 0
+		for _, f := range r.frames[i+1:] {
+			if f.dir == DirClient {
+				break
+			}
+			out := f.raw
+			if f.msg.Method == "" {
+				// Responses go only to the request they answer.
+				if answered || string(f.msg.ID) != string(recordedID) {
+					continue
+				}
+				out, answered = r.response(recordedID, msg.ID), true
+			}
+			if err := send(out); err != nil {
+				return
+			}
+		}
+		if !answered {
+			if err := send(r.response(recordedID, msg.ID)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// match finds the first unused recorded client message for method, with
+// equal params if there is one.
+func (r *Replayer) match(used []bool, method, params string) int {
+	fallback := -1
+	for i, f := range r.frames {
+		if used[i] || f.dir != DirClient || f.msg.Method != method {
+			continue
+		}
+		if f.params == params {
+			return i
+		}
+		if fallback < 0 {
+			fallback = i
+		}
+	}
+	return fallback
+}
+
+// response returns the recorded response to recordedID, re-addressed to id.
+func (r *Replayer) response(recordedID, id json.RawMessage) []byte {
+	var msg Message
+	if raw, ok := r.responses[string(recordedID)]; ok {
+		_ = json.Unmarshal(raw, &msg)
+	} else {
+		// gopls never answered, e.g. the request was cancelled.
+		msg.Error = &ResponseError{Code: codeInternalError, Message: "request was not answered in the recording"}
+	}
+	msg.JSONRPC = "2.0"
+	msg.ID = id
+	data, _ := json.Marshal(&msg)
+	return data
+}
+
+// canonicalJSON re-encodes raw with sorted keys, so equal params compare equal.
+func canonicalJSON(raw json.RawMessage) string {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
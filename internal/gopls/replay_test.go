@@ -0,0 +1,63 @@
+package gopls
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// replayRecording answers two hovers that differ only in their position.
+const replayRecording = `{"dir":"client","elapsed":0,"message":{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}}
+{"dir":"server","elapsed":1,"message":{"jsonrpc":"2.0","id":1,"result":{"capabilities":{}}}}
+{"dir":"client","elapsed":2,"message":{"jsonrpc":"2.0","method":"initialized","params":{}}}
+{"dir":"client","elapsed":3,"message":{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{"textDocument":{"uri":"file://$ROOT/a.go"},"position":{"line":1,"character":0}}}}
+{"dir":"server","elapsed":4,"message":{"jsonrpc":"2.0","id":2,"result":{"contents":{"kind":"markdown","value":"first"}}}}
+{"dir":"client","elapsed":5,"message":{"jsonrpc":"2.0","id":3,"method":"textDocument/hover","params":{"textDocument":{"uri":"file://$ROOT/a.go"},"position":{"line":2,"character":0}}}}
+{"dir":"server","elapsed":6,"message":{"jsonrpc":"2.0","id":3,"result":{"contents":{"kind":"markdown","value":"second"}}}}
+`
+
+func TestReplayerMatchesParams(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "session.jsonl")
+	if err := os.WriteFile(path, []byte(replayRecording), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root := "/work/src"
+	r, err := NewReplayer(path, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(&Config{Dial: r.Dial, MaxRestarts: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+	if err := c.Initialize(ctx, "file://"+root, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	hover := func(line int) string {
+		t.Helper()
+		raw, err := c.SendRequest(ctx, "textDocument/hover", map[string]any{
+			"position":     map[string]any{"character": 0, "line": line},
+			"textDocument": map[string]any{"uri": "file://" + root + "/a.go"},
+		})
+		if err != nil {
+			t.Fatalf("hover line %d: %v", line, err)
+		}
+		return string(raw)
+	}
+	// Out of recorded order: the params, not the order, pick the response.
+	if got := hover(2); !strings.Contains(got, "second") {
+		t.Errorf("hover line 2 = %s, want second", got)
+	}
+	if got := hover(1); !strings.Contains(got, "first") {
+		t.Errorf("hover line 1 = %s, want first", got)
+	}
+	if _, err := c.SendRequest(ctx, "textDocument/definition", map[string]any{}); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("unrecorded request: err = %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package gopls
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// RequestHandler answers a request sent by gopls to the client. The result is
+// marshaled as the response; returning a *ResponseError sets its error code.
+type RequestHandler func(params json.RawMessage) (interface{}, error)
+
+// OnRequest sets the handler for a server-to-client request method,
+// replacing any previous (including default) handler.
+func (c *Client) OnRequest(method string, handler RequestHandler) {
+	c.notifyMu.Lock()
+	defer c.notifyMu.Unlock()
+	c.handlers[method] = handler
+}
+
+// registerDefaultHandlers installs replies that keep gopls going when the
+// embedding code does not care about a request.
+func (c *Client) registerDefaultHandlers() {
+	// One result per requested item: the configured settings for the
+	// "gopls" section, null (= defaults) for anything else.
+	c.handlers["workspace/configuration"] = func(params json.RawMessage) (interface{}, error) {
+		var p struct {
+			Items []struct {
+				Section string `json:"section"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		out := make([]interface{}, len(p.Items))
+		for i, item := range p.Items {
+			out[i] = c.cfg.Settings.settingsFor(item.Section)
+		}
+		return out, nil
+	}
+	c.handlers["workspace/workspaceFolders"] = func(json.RawMessage) (interface{}, error) {
+		c.stateMu.Lock()
+		defer c.stateMu.Unlock()
+		if c.initParams == nil {
+			return nil, nil
+		}
+		return c.initParams["workspaceFolders"], nil
+	}
+	accept := func(json.RawMessage) (interface{}, error) { return nil, nil }
+	c.handlers["window/workDoneProgress/create"] = accept
+	c.handlers["client/registerCapability"] = accept
+	c.handlers["client/unregisterCapability"] = accept
+	c.handlers["window/showMessageRequest"] = accept
+	c.handlers["window/showDocument"] = func(json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{"success": false}, nil
+	}
+	c.handlers["workspace/applyEdit"] = func(json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{
+			"applied":       false,
+			"failureReason": "client does not apply edits",
+		}, nil
+	}
+}
+
+// dispatchRequest runs the handler for a server request and writes the reply.
+func (c *Client) dispatchRequest(msg *Message) {
+	c.notifyMu.RLock()
+	handler := c.handlers[msg.Method]
+	c.notifyMu.RUnlock()
+
+	reply := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      msg.ID,
+	}
+	if handler == nil {
+		reply["error"] = &ResponseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+		_ = c.writeMessage(reply)
+		return
+	}
+
+	result, err := handler(msg.Params)
+	if err != nil {
+		var respErr *ResponseError
+		if !errors.As(err, &respErr) {
+			respErr = &ResponseError{Code: codeInternalError, Message: err.Error()}
+		}
+		reply["error"] = respErr
+	} else {
+		reply["result"] = result
+	}
+	_ = c.writeMessage(reply)
+}
@@ -0,0 +1,138 @@
+package gopls
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Settings are gopls user settings, sent as initializationOptions and in
+// answer to workspace/configuration requests.
+// See https://github.com/golang/tools/blob/master/gopls/doc/settings.md.
+type Settings struct {
+	BuildFlags       []string          // e.g. ["-tags=integration"]
+	Env              map[string]string // e.g. GOFLAGS, GOPRIVATE
+	DirectoryFilters []string          // e.g. ["-node_modules", "-output"]
+	Analyses         map[string]bool   // Analyzer name → enabled
+	Staticcheck      *bool
+	// Extra holds any other gopls setting, passed through as-is.
+	Extra map[string]interface{}
+}
+
+// Map returns the settings in the form gopls expects.
+func (s *Settings) Map() map[string]interface{} {
+	out := make(map[string]interface{})
+	if s == nil {
+		return out
+	}
+	for k, v := range s.Extra {
+		out[k] = v
+	}
+	if len(s.BuildFlags) > 0 {
+		out["buildFlags"] = s.BuildFlags
+	}
+	if len(s.Env) > 0 {
+		out["env"] = s.Env
+	}
+	if len(s.DirectoryFilters) > 0 {
+		out["directoryFilters"] = s.DirectoryFilters
+	}
+	if len(s.Analyses) > 0 {
+		out["analyses"] = s.Analyses
+	}
+	if s.Staticcheck != nil {
+		out["staticcheck"] = *s.Staticcheck
+	}
+	return out
+}
+
+// LoadSettingsFile reads gopls settings from a JSON object, using the same
+// keys as editor configuration (e.g. {"buildFlags": ["-tags=e2e"]}).
+func LoadSettingsFile(path string) (*Settings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := parseSettings(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse gopls settings %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// SettingsFromMap converts settings decoded from a config file.
+func SettingsFromMap(m map[string]interface{}) (*Settings, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return parseSettings(data)
+}
+
+func parseSettings(data []byte) (*Settings, error) {
+	var typed struct {
+		BuildFlags       []string          `json:"buildFlags"`
+		Env              map[string]string `json:"env"`
+		DirectoryFilters []string          `json:"directoryFilters"`
+		Analyses         map[string]bool   `json:"analyses"`
+		Staticcheck      *bool             `json:"staticcheck"`
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	for _, k := range []string{"buildFlags", "env", "directoryFilters", "analyses", "staticcheck"} {
+		delete(raw, k)
+	}
+	return &Settings{
+		BuildFlags:       typed.BuildFlags,
+		Env:              typed.Env,
+		DirectoryFilters: typed.DirectoryFilters,
+		Analyses:         typed.Analyses,
+		Staticcheck:      typed.Staticcheck,
+		Extra:            raw,
+	}, nil
+}
+
+// Merge adds other on top of s: lists are appended, map entries and single
+// values from other win.
+func (s *Settings) Merge(other *Settings) {
+	if other == nil {
+		return
+	}
+	s.BuildFlags = append(s.BuildFlags, other.BuildFlags...)
+	s.DirectoryFilters = append(s.DirectoryFilters, other.DirectoryFilters...)
+	for k, v := range other.Env {
+		if s.Env == nil {
+			s.Env = make(map[string]string)
+		}
+		s.Env[k] = v
+	}
+	for k, v := range other.Analyses {
+		if s.Analyses == nil {
+			s.Analyses = make(map[string]bool)
+		}
+		s.Analyses[k] = v
+	}
+	if other.Staticcheck != nil {
+		s.Staticcheck = other.Staticcheck
+	}
+	for k, v := range other.Extra {
+		if s.Extra == nil {
+			s.Extra = make(map[string]interface{})
+		}
+		s.Extra[k] = v
+	}
+}
+
+// settingsFor answers one workspace/configuration item. gopls asks for the
+// "gopls" section, optionally scoped to a folder.
+func (s *Settings) settingsFor(section string) interface{} {
+	if section != "" && section != "gopls" {
+		return nil
+	}
+	return s.Map()
+}
@@ -0,0 +1,291 @@
+package gopls
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const (
+	defaultMaxRestarts = 5
+	// A process that stayed up this long resets the consecutive restart count.
+	stableUptime   = time.Minute
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 30 * time.Second
+)
+
+// process is one connection to gopls: a spawned `gopls serve` over stdio, or
+// a socket to a shared gopls daemon (cmd is nil then).
+type process struct {
+	cmd     *exec.Cmd
+	conn    io.WriteCloser // stdin of cmd, or the socket
+	writer  *bufio.Writer
+	started time.Time
+
+	done    chan struct{} // Closed once the process has exited or the connection dropped
+	waitErr error
+}
+
+// start connects to gopls and makes the connection current.
+func (c *Client) start() error {
+	var (
+		proc   *process
+		reader io.Reader
+		err    error
+	)
+	switch {
+	case c.cfg.Dial != nil:
+		var conn io.ReadWriteCloser
+		if conn, err = c.cfg.Dial(); err == nil {
+			proc, reader = &process{conn: conn}, conn
+		}
+	case c.isRemote():
+		proc, reader, err = c.dialRemote()
+	default:
+		proc, reader, err = c.spawn()
+	}
+	if err != nil {
+		return err
+	}
+	proc.writer = bufio.NewWriter(proc.conn)
+	proc.started = time.Now()
+	proc.done = make(chan struct{})
+
+	c.writeMu.Lock()
+	c.proc = proc
+	c.writeMu.Unlock()
+
+	go func() {
+		err := c.readLoop(bufio.NewReader(reader))
+		// Hold new requests back before failing the pending ones, so that
+		// callers retrying right away wait for the restart.
+		select {
+		case <-c.closed:
+		default:
+			if c.currentProcess() == proc {
+				c.markRestarting()
+			}
+		}
+		c.closePending(fmt.Errorf("gopls exited: %w", err))
+		if proc.cmd != nil {
+			proc.waitErr = proc.cmd.Wait()
+		} else {
+			_ = proc.conn.Close()
+			proc.waitErr = fmt.Errorf("connection lost: %w", err)
+		}
+		close(proc.done)
+		select {
+		case c.exited <- proc:
+		case <-c.closed:
+		}
+	}()
+	return nil
+}
+
+// spawn runs `gopls serve` with stdio pipes. With Remote "auto", gopls
+// itself forwards to (and if needed starts) a shared daemon.
+func (c *Client) spawn() (*process, io.Reader, error) {
+	args := append([]string{"serve"}, c.cfg.Args...)
+	if c.cfg.Remote == "auto" {
+		args = append(args, "-remote=auto")
+	}
+	cmd := exec.Command(c.goplsPath(), args...)
+	if c.cfg.Workdir != "" {
+		cmd.Dir = c.cfg.Workdir
+	}
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("gopls stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("gopls stdout: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("start gopls: %w", err)
+	}
+	return &process{cmd: cmd, conn: stdin}, stdout, nil
+}
+
+func (c *Client) goplsPath() string {
+	if c.cfg.GoplsPath != "" {
+		return c.cfg.GoplsPath
+	}
+	return "gopls"
+}
+
+// stop closes the connection and waits briefly for the process to exit,
+// killing it if it does not.
+func (p *process) stop() {
+	_ = p.conn.Close()
+	select {
+	case <-p.done:
+		return
+	case <-time.After(2 * time.Second):
+	}
+	if p.cmd != nil {
+		_ = p.cmd.Process.Kill()
+	}
+	<-p.done
+}
+
+// supervise restarts gopls whenever the current process exits unexpectedly.
+func (c *Client) supervise() {
+	crashes := 0
+	for {
+		var proc *process
+		select {
+		case <-c.closed:
+			return
+		case proc = <-c.exited:
+		}
+
+		if proc != c.currentProcess() {
+			continue // A process we already replaced
+		}
+		if time.Since(proc.started) >= stableUptime {
+			crashes = 0
+		}
+
+		c.markRestarting()
+		cause := proc.waitErr
+		if cause == nil {
+			cause = errors.New("exited")
+		}
+		err := c.restart(&crashes, cause)
+
+		c.stateMu.Lock()
+		if err != nil {
+			c.deadErr = err
+		} else {
+			c.restarts++
+		}
+		close(c.ready)
+		c.stateMu.Unlock()
+		if err != nil {
+			log.Printf("gopls: %v", err)
+			return
+		}
+	}
+}
+
+// restart respawns gopls with exponential backoff, replays initialize and
+// runs the restart hooks (e.g. re-opening documents).
+func (c *Client) restart(crashes *int, cause error) error {
+	for {
+		*crashes++
+		if c.cfg.MaxRestarts < 0 || *crashes > c.cfg.MaxRestarts {
+			return fmt.Errorf("gopls exited (%v) and was not restarted after %d attempts", cause, *crashes-1)
+		}
+		backoff := initialBackoff << (*crashes - 1)
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		log.Printf("gopls: process exited (%v), restarting in %v (attempt %d/%d)", cause, backoff, *crashes, c.cfg.MaxRestarts)
+		select {
+		case <-c.closed:
+			return errors.New("client closed")
+		case <-time.After(backoff):
+		}
+
+		if err := c.start(); err != nil {
+			cause = err
+			continue
+		}
+		if err := c.reinitialize(); err != nil {
+			cause = err
+			c.currentProcess().stop()
+			select { // Drain the exit of the process we just stopped
+			case <-c.exited:
+			case <-c.closed:
+				return errors.New("client closed")
+			}
+			continue
+		}
+		return nil
+	}
+}
+
+func (c *Client) reinitialize() error {
+	c.stateMu.Lock()
+	params := c.initParams
+	hooks := append([]func(context.Context){}, c.restartHooks...)
+	c.stateMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+	if params != nil {
+		c.progress.reset()
+		if _, err := c.request(ctx, "initialize", params); err != nil {
+			return fmt.Errorf("initialize: %w", err)
+		}
+		if err := c.notification("initialized", map[string]interface{}{}); err != nil {
+			return fmt.Errorf("initialized: %w", err)
+		}
+		c.progress.armGracePeriod()
+	}
+	for _, hook := range hooks {
+		hook(ctx)
+	}
+	return nil
+}
+
+func (c *Client) currentProcess() *process {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.proc
+}
+
+// markRestarting makes waitReady block until the supervisor closes c.ready.
+func (c *Client) markRestarting() {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	select {
+	case <-c.ready:
+		c.ready = make(chan struct{})
+	default: // Already restarting
+	}
+}
+
+// waitReady blocks while gopls is being restarted.
+func (c *Client) waitReady(ctx context.Context) error {
+	c.stateMu.Lock()
+	ready := c.ready
+	c.stateMu.Unlock()
+
+	select {
+	case <-ready:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.closed:
+		return errors.New("client closed")
+	}
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	return c.deadErr
+}
+
+// onRestart registers a hook that runs after gopls has been restarted and
+// re-initialized, before other requests are let through. Hooks must use
+// request/notification rather than the blocking Send* methods.
+func (c *Client) onRestart(hook func(ctx context.Context)) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	c.restartHooks = append(c.restartHooks, hook)
+}
+
+// Restarts returns how many times gopls has been restarted after exiting unexpectedly.
+func (c *Client) Restarts() int {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	return c.restarts
+}
@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+// maxFixLookups bounds the codeAction requests made for one analyze_code call.
+const maxFixLookups = 20
+
+func (s *session) AnalyzeCode(ctx context.Context, _ *sdk.CallToolRequest, input tools.AnalyzeCodeInput) (*sdk.CallToolResult, tools.AnalyzeCodeOutput, error) {
+	if input.Code == "" {
+		return nil, tools.AnalyzeCodeOutput{}, errors.New("code is required")
+	}
+	if input.FilePath == "" {
+		return nil, tools.AnalyzeCodeOutput{}, errors.New("file_path is required")
+	}
+	wait := time.Duration(input.WaitMs) * time.Millisecond
+	if wait <= 0 {
+		wait = 3 * time.Second
+	}
+	if wait > 30*time.Second {
+		wait = 30 * time.Second
+	}
+
+	if err := s.Initialize(ctx); err != nil {
+		return nil, tools.AnalyzeCodeOutput{}, err
+	}
+	path, err := s.resolveOverlayPath(input.FilePath)
+	if err != nil {
+		return nil, tools.AnalyzeCodeOutput{}, err
+	}
+	uri, version, release, err := s.openOverlay(ctx, path, input.Code)
+	if err != nil {
+		return nil, tools.AnalyzeCodeOutput{}, err
+	}
+	defer release()
+
+	output := tools.AnalyzeCodeOutput{FilePath: path, Diagnostics: []tools.Diagnostic{}}
+	// Pushed diagnostics of an older version describe other content; fall
+	// back to a pull, which always reflects the current buffer.
+	diags, ok := s.diagnostics.WaitVersion(uri, version, wait)
+	if !ok {
+		diags = s.pullDiagnostics(ctx, uri, s.opts.WarmupTimeout)
+		output.Notice = "gopls did not publish diagnostics within wait_ms; results may be incomplete"
+	}
+
+	for _, d := range mergeDiagnostics(diags) {
+		if d.Severity != "error" && !input.IncludeWarnings {
+			continue
+		}
+		if len(output.Diagnostics) < maxFixLookups {
+			d.Suggestion = s.suggestFixes(ctx, uri, d)
+		}
+		output.Diagnostics = append(output.Diagnostics, d)
+	}
+	return nil, output, nil
+}
+
+// suggestFixes returns the titles of the gopls quick fixes for d, or "".
+func (s *session) suggestFixes(ctx context.Context, uri string, d tools.Diagnostic) string {
+	lsp := tools.DiagnosticToLSP(d)
+	params := map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"range":        lsp["range"],
+		"context": map[string]any{
+			"diagnostics": []any{lsp},
+			"only":        []string{"quickfix"},
+		},
+	}
+	raw, err := s.client.SendRequest(ctx, "textDocument/codeAction", params)
+	if err != nil {
+		return ""
+	}
+	titles, err := tools.ParseCodeActionTitles(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.Join(titles, "; ")
+}
@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dreamcats/bytelsp/internal/gopls/goplstest"
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+const snippet = "package main\n\nfunc main() { fmt.Println(\"hi\") }\n"
+
+// lspDiag returns an LSP diagnostic on line 3 (1-based) of snippet.
+func lspDiag(severity int, message string) map[string]any {
+	return map[string]any{
+		"range": map[string]any{
+			"start": map[string]any{"line": 2, "character": 14},
+			"end":   map[string]any{"line": 2, "character": 17},
+		},
+		"severity": severity,
+		"source":   "compiler",
+		"message":  message,
+	}
+}
+
+func TestAnalyzeCodeWaitsForVersion(t *testing.T) {
+	root := fakeRoot(t)
+	f := goplstest.NewServer()
+	f.Handle("textDocument/didOpen", func(raw json.RawMessage) (interface{}, error) {
+		var params struct {
+			TextDocument struct {
+				URI     string `json:"uri"`
+				Version int    `json:"version"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, err
+		}
+		doc := params.TextDocument
+		// Diagnostics of older content first: they must not be used.
+		_ = f.Notify("textDocument/publishDiagnostics", map[string]any{
+			"uri": doc.URI, "version": doc.Version - 1,
+			"diagnostics": []any{lspDiag(1, "stale")},
+		})
+		return nil, f.Notify("textDocument/publishDiagnostics", map[string]any{
+			"uri": doc.URI, "version": doc.Version,
+			"diagnostics": []any{lspDiag(1, "undefined: fmt"), lspDiag(2, "unused result")},
+		})
+	})
+	f.Respond("textDocument/codeAction", []map[string]any{
+		{"title": "Organize imports", "kind": "quickfix"},
+		{"title": `Add import: "fmt"`, "kind": "quickfix", "isPreferred": true},
+	})
+	cs, _ := connect(t, Options{Root: root, GoplsDial: f.Dial})
+
+	var out tools.AnalyzeCodeOutput
+	callTool(t, cs, "analyze_code", map[string]any{"file_path": "snippet.go", "code": snippet}, &out)
+	if out.Notice != "" {
+		t.Errorf("notice = %q, want none", out.Notice)
+	}
+	if out.FilePath != filepath.Join(root, "snippet.go") {
+		t.Errorf("file_path = %s", out.FilePath)
+	}
+	want := tools.Diagnostic{
+		Line: 3, Col: 15, EndLine: 3, EndCol: 18, Severity: "error",
+		Message: "undefined: fmt", Source: "compiler",
+		Suggestion: `Add import: "fmt"; Organize imports`,
+	}
+	if len(out.Diagnostics) != 1 || out.Diagnostics[0] != want {
+		t.Errorf("diagnostics = %+v, want [%+v]", out.Diagnostics, want)
+	}
+
+	callTool(t, cs, "analyze_code", map[string]any{"file_path": "snippet.go", "code": snippet, "include_warnings": true}, &out)
+	if len(out.Diagnostics) != 2 || out.Diagnostics[1].Severity != "warning" {
+		t.Errorf("with include_warnings: %+v", out.Diagnostics)
+	}
+	// The overlay is closed again after each call.
+	waitFor(t, "didClose", func() bool { return len(f.Received("textDocument/didClose")) == 2 })
+}
+
+func TestAnalyzeCodeFallsBackToPull(t *testing.T) {
+	root := fakeRoot(t)
+	f := goplstest.NewServer()
+	f.Respond("textDocument/diagnostic", map[string]any{
+		"kind":  "full",
+		"items": []any{lspDiag(1, "undefined: fmt")},
+	})
+	cs, _ := connect(t, Options{Root: root, GoplsDial: f.Dial})
+
+	var out tools.AnalyzeCodeOutput
+	callTool(t, cs, "analyze_code", map[string]any{"file_path": "snippet.go", "code": snippet, "wait_ms": 50}, &out)
+	if !strings.Contains(out.Notice, "did not publish diagnostics") {
+		t.Errorf("notice = %q, want the pull fallback notice", out.Notice)
+	}
+	if len(out.Diagnostics) != 1 || out.Diagnostics[0].Message != "undefined: fmt" || out.Diagnostics[0].Suggestion != "" {
+		t.Errorf("diagnostics = %+v, want the pulled error without fixes", out.Diagnostics)
+	}
+	if got := len(f.Received("textDocument/diagnostic")); got != 1 {
+		t.Errorf("textDocument/diagnostic sent %d times, want 1", got)
+	}
+}
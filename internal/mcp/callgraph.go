@@ -0,0 +1,204 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+const (
+	maxCallDepth     = 5
+	defaultMaxFanout = 20
+	defaultMaxNodes  = 200
+)
+
+// callGraphOptions controls multi-level call hierarchy traversal.
+type callGraphOptions struct {
+	depth        int
+	maxFanout    int
+	maxNodes     int
+	samePackage  bool
+	excludeTests bool
+}
+
+// callGraphBuilder walks callHierarchy/incomingCalls and outgoingCalls
+// breadth-first, deduplicating functions that were already visited.
+type callGraphBuilder struct {
+	s        *session
+	opts     callGraphOptions
+	roots    []string
+	rootPkg  string
+	ids      map[string]string
+	nodes    []tools.CallGraphNode
+	edges    map[string]bool
+	out      []tools.CallGraphEdge
+	lines    map[string][]string
+	truncate bool
+}
+
+type callGraphEntry struct {
+	item  tools.LSPCallHierarchyItem
+	depth int
+}
+
+func newCallGraphBuilder(s *session, root tools.LSPCallHierarchyItem, opts callGraphOptions) *callGraphBuilder {
+	b := &callGraphBuilder{
+		s:       s,
+		opts:    opts,
+		roots:   s.workspaceRoots(),
+		rootPkg: tools.CallHierarchyPackage(root.Detail),
+		ids:     make(map[string]string),
+		edges:   make(map[string]bool),
+		lines:   make(map[string][]string),
+	}
+	b.addNode(root, 0)
+	return b
+}
+
+// Walk expands the graph from root in the given direction ("incoming" or "outgoing").
+func (b *callGraphBuilder) Walk(ctx context.Context, root tools.LSPCallHierarchyItem, direction string) {
+	visited := map[string]bool{itemKey(root): true}
+	queue := []callGraphEntry{{item: root, depth: 0}}
+	for len(queue) > 0 {
+		if ctx.Err() != nil {
+			b.truncate = true
+			return
+		}
+		entry := queue[0]
+		queue = queue[1:]
+		if entry.depth >= b.opts.depth || !b.expandable(entry.item, root) {
+			continue
+		}
+
+		calls, err := b.fetch(ctx, entry.item, direction)
+		if err != nil {
+			continue
+		}
+		followed := 0
+		for _, call := range calls {
+			if b.opts.excludeTests && strings.HasSuffix(tools.URIToPath(call.Item.URI), "_test.go") {
+				continue
+			}
+			if followed >= b.opts.maxFanout {
+				b.truncate = true
+				break
+			}
+			key := itemKey(call.Item)
+			if _, known := b.ids[key]; !known && len(b.nodes) >= b.opts.maxNodes {
+				b.truncate = true
+				break
+			}
+			followed++
+			id := b.addNode(call.Item, entry.depth+1)
+			current := b.ids[itemKey(entry.item)]
+
+			// The call site of an incoming call lives in the caller; for an
+			// outgoing call it lives in the function being expanded.
+			if direction == "incoming" {
+				b.addEdge(id, current, tools.URIToPath(call.Item.URI), call.CallSiteLine())
+			} else {
+				b.addEdge(current, id, tools.URIToPath(entry.item.URI), call.CallSiteLine())
+			}
+
+			if !visited[key] {
+				visited[key] = true
+				queue = append(queue, callGraphEntry{item: call.Item, depth: entry.depth + 1})
+			}
+		}
+	}
+}
+
+func (b *callGraphBuilder) fetch(ctx context.Context, item tools.LSPCallHierarchyItem, direction string) ([]tools.LSPCallHierarchyCall, error) {
+	params := map[string]any{
+		"item": tools.ConvertToLSPCallHierarchyItem(item),
+	}
+	if direction == "incoming" {
+		raw, err := b.s.client.SendRequest(ctx, "callHierarchy/incomingCalls", params)
+		if err != nil {
+			return nil, err
+		}
+		return tools.ParseCallHierarchyIncomingCalls(raw)
+	}
+	raw, err := b.s.client.SendRequest(ctx, "callHierarchy/outgoingCalls", params)
+	if err != nil {
+		return nil, err
+	}
+	return tools.ParseCallHierarchyOutgoingCalls(raw)
+}
+
+// expandable reports whether the calls of item should be followed.
+// Functions outside the workspace are kept as leaves.
+func (b *callGraphBuilder) expandable(item, root tools.LSPCallHierarchyItem) bool {
+	if itemKey(item) == itemKey(root) {
+		return true
+	}
+	if !inWorkspace(b.roots, tools.URIToPath(item.URI)) {
+		return false
+	}
+	if b.opts.samePackage && tools.CallHierarchyPackage(item.Detail) != b.rootPkg {
+		return false
+	}
+	return true
+}
+
+func (b *callGraphBuilder) addNode(item tools.LSPCallHierarchyItem, depth int) string {
+	key := itemKey(item)
+	if id, ok := b.ids[key]; ok {
+		return id
+	}
+	id := fmt.Sprintf("n%d", len(b.nodes))
+	b.ids[key] = id
+	b.nodes = append(b.nodes, tools.CallGraphNode{
+		ID:       id,
+		Name:     item.Name,
+		Kind:     tools.SymbolKindToString(item.Kind),
+		FilePath: tools.URIToPath(item.URI),
+		Line:     item.Line(),
+		Col:      item.SelectionRange.Start.Character + 1,
+		Detail:   item.Detail,
+		Package:  tools.CallHierarchyPackage(item.Detail),
+		Depth:    depth,
+	})
+	return id
+}
+
+func (b *callGraphBuilder) addEdge(from, to, filePath string, line int) {
+	key := from + "->" + to
+	if b.edges[key] {
+		return
+	}
+	b.edges[key] = true
+	b.out = append(b.out, tools.CallGraphEdge{
+		From:    from,
+		To:      to,
+		Line:    line,
+		Context: b.lineContent(filePath, line),
+	})
+}
+
+// lineContent is getLineContent with a per-traversal file cache.
+func (b *callGraphBuilder) lineContent(filePath string, lineNum int) string {
+	lines, ok := b.lines[filePath]
+	if !ok {
+		if data, err := os.ReadFile(filePath); err == nil {
+			lines = strings.Split(string(data), "\n")
+		}
+		b.lines[filePath] = lines
+	}
+	if lineNum < 1 || lineNum > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[lineNum-1])
+}
+
+// Result returns the collected nodes and edges.
+func (b *callGraphBuilder) Result() ([]tools.CallGraphNode, []tools.CallGraphEdge, bool) {
+	return b.nodes, b.out, b.truncate
+}
+
+func itemKey(item tools.LSPCallHierarchyItem) string {
+	return fmt.Sprintf("%s:%d:%d", item.URI, item.SelectionRange.Start.Line, item.SelectionRange.Start.Character)
+}
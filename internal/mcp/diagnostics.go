@@ -0,0 +1,277 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+// severityRank orders diagnostic severities from most to least severe.
+var severityRank = map[string]int{
+	"error":   1,
+	"warning": 2,
+	"info":    3,
+	"hint":    4,
+}
+
+func (s *session) GetDiagnostics(ctx context.Context, _ *sdk.CallToolRequest, input tools.GetDiagnosticsInput) (*sdk.CallToolResult, tools.GetDiagnosticsOutput, error) {
+	minSeverity := input.MinSeverity
+	if minSeverity == "" {
+		minSeverity = "error"
+	}
+	maxRank, ok := severityRank[minSeverity]
+	if !ok {
+		return nil, tools.GetDiagnosticsOutput{}, errors.New("min_severity must be 'error', 'warning', 'info', or 'hint'")
+	}
+	wait := time.Duration(input.WaitMs) * time.Millisecond
+	if wait <= 0 {
+		wait = 3 * time.Second
+	}
+	if wait > 30*time.Second {
+		wait = 30 * time.Second
+	}
+	maxResults := input.MaxResults
+	if maxResults <= 0 {
+		maxResults = 100
+	}
+
+	if err := s.Initialize(ctx); err != nil {
+		return nil, tools.GetDiagnosticsOutput{}, err
+	}
+
+	var (
+		scope  string
+		notice string
+		byFile map[string][]tools.Diagnostic
+		err    error
+	)
+	switch {
+	case input.Code != "":
+		scope = "file"
+		var path string
+		switch {
+		case input.FilePath != "":
+			path, err = s.resolveOverlayPath(input.FilePath)
+		case input.Package != "":
+			scope = "package"
+			var release func()
+			path, release, err = s.snippetPath(input.Package)
+			if err == nil {
+				defer release()
+			}
+		default:
+			err = errors.New("code needs file_path or package")
+		}
+		if err != nil {
+			return nil, tools.GetDiagnosticsOutput{}, err
+		}
+		byFile, err = s.collectOverlayDiagnostics(ctx, path, input.Code, wait)
+	case input.FilePath != "":
+		scope = "file"
+		absPath, rerr := s.resolveDiskPath(input.FilePath)
+		if rerr != nil {
+			return nil, tools.GetDiagnosticsOutput{}, rerr
+		}
+		byFile, err = s.collectFileDiagnostics(ctx, []string{absPath}, wait)
+	case input.Package != "":
+		scope = "package"
+		files, rerr := s.resolvePackageFiles(input.Package)
+		if rerr != nil {
+			return nil, tools.GetDiagnosticsOutput{}, rerr
+		}
+		byFile, err = s.collectFileDiagnostics(ctx, files, wait)
+	default:
+		scope = "workspace"
+		notice = s.awaitWorkspaceLoad(ctx, input.NoIndexWait)
+		byFile = s.collectWorkspaceDiagnostics(ctx, wait)
+	}
+	if err != nil {
+		return nil, tools.GetDiagnosticsOutput{}, err
+	}
+
+	output := tools.GetDiagnosticsOutput{Scope: scope, Files: []tools.FileDiagnostics{}}
+	paths := make([]string, 0, len(byFile))
+	for path := range byFile {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	returned := 0
+	for _, path := range paths {
+		var kept []tools.Diagnostic
+		for _, d := range byFile[path] {
+			rank, ok := severityRank[d.Severity]
+			if !ok || rank > maxRank {
+				continue
+			}
+			switch d.Severity {
+			case "error":
+				output.ErrorCount++
+			case "warning":
+				output.WarningCount++
+			}
+			if returned >= maxResults {
+				output.Truncated = true
+				continue
+			}
+			kept = append(kept, d)
+			returned++
+		}
+		if len(kept) > 0 {
+			output.Files = append(output.Files, tools.FileDiagnostics{FilePath: path, Diagnostics: kept})
+		}
+	}
+	output.Notice = notice
+	return nil, output, nil
+}
+
+// collectFileDiagnostics opens each file in gopls with its content on disk
+// and gathers its diagnostics.
+func (s *session) collectFileDiagnostics(ctx context.Context, paths []string, wait time.Duration) (map[string][]tools.Diagnostic, error) {
+	uris := make(map[string]string, len(paths))
+	for _, path := range paths {
+		_, uri, err := s.prepareDocument(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		uris[path] = uri
+	}
+	return s.awaitDiagnostics(ctx, uris, wait), nil
+}
+
+// collectOverlayDiagnostics checks code as the unsaved content of path and
+// closes the buffer again afterwards.
+func (s *session) collectOverlayDiagnostics(ctx context.Context, path, code string, wait time.Duration) (map[string][]tools.Diagnostic, error) {
+	uri, _, release, err := s.openOverlay(ctx, path, code)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return s.awaitDiagnostics(ctx, map[string]string{path: uri}, wait), nil
+}
+
+// awaitDiagnostics gathers the diagnostics of open documents (path to URI)
+// from a textDocument/diagnostic pull merged with the latest pushed results.
+func (s *session) awaitDiagnostics(ctx context.Context, uris map[string]string, wait time.Duration) map[string][]tools.Diagnostic {
+	deadline := time.Now().Add(wait)
+	out := make(map[string][]tools.Diagnostic, len(uris))
+	for path, uri := range uris {
+		remaining := time.Until(deadline)
+		if remaining < 200*time.Millisecond {
+			remaining = 200 * time.Millisecond
+		}
+		pulled := s.pullDiagnostics(ctx, uri, remaining)
+		pushed, ok := s.diagnostics.Latest(uri)
+		if !ok && pulled == nil {
+			pushed = s.diagnostics.Wait(uri, time.Until(deadline))
+		}
+		out[path] = mergeDiagnostics(pulled, pushed)
+	}
+	return out
+}
+
+// collectWorkspaceDiagnostics waits for gopls to stop publishing and returns
+// every cached diagnostic that belongs to the workspace.
+func (s *session) collectWorkspaceDiagnostics(ctx context.Context, wait time.Duration) map[string][]tools.Diagnostic {
+	s.diagnostics.WaitQuiet(ctx, 500*time.Millisecond, wait)
+	roots := s.workspaceRoots()
+	out := make(map[string][]tools.Diagnostic)
+	for uri, diags := range s.diagnostics.Snapshot() {
+		path := tools.URIToPath(uri)
+		if len(diags) == 0 || !inWorkspace(roots, path) {
+			continue
+		}
+		out[path] = diags
+	}
+	return out
+}
+
+func (s *session) pullDiagnostics(ctx context.Context, uri string, timeout time.Duration) []tools.Diagnostic {
+	pullCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	params := map[string]interface{}{
+		"textDocument": map[string]interface{}{
+			"uri": uri,
+		},
+	}
+	raw, err := s.client.SendRequest(pullCtx, "textDocument/diagnostic", params)
+	if err != nil {
+		return nil
+	}
+	diags, err := tools.ParseDiagnostics(raw, uri)
+	if err != nil {
+		return nil
+	}
+	return diags
+}
+
+// resolvePackageFiles returns the absolute paths of the Go files in pkg,
+// which is either a package directory or an import path.
+func (s *session) resolvePackageFiles(pkg string) ([]string, error) {
+	dir := pkg
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(s.root, pkg)
+	}
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+				continue
+			}
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no Go files found in %s", dir)
+		}
+		return files, nil
+	}
+
+	info, err := tools.ResolveImportPath(s.root, pkg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve package: %w", err)
+	}
+	if info.Dir == "" || len(info.GoFiles) == 0 {
+		return nil, fmt.Errorf("package %s has no Go files", pkg)
+	}
+	files := make([]string, 0, len(info.GoFiles))
+	for _, name := range info.GoFiles {
+		files = append(files, filepath.Join(info.Dir, name))
+	}
+	return files, nil
+}
+
+// mergeDiagnostics combines pulled and pushed diagnostics, dropping duplicates.
+func mergeDiagnostics(lists ...[]tools.Diagnostic) []tools.Diagnostic {
+	seen := make(map[string]bool)
+	var out []tools.Diagnostic
+	for _, list := range lists {
+		for _, d := range list {
+			key := fmt.Sprintf("%d:%d:%s", d.Line, d.Col, d.Message)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			out = append(out, d)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Line != out[j].Line {
+			return out[i].Line < out[j].Line
+		}
+		return out[i].Col < out[j].Col
+	})
+	return out
+}
@@ -0,0 +1,25 @@
+package mcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dreamcats/bytelsp/internal/gopls/goplstest"
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+func TestDiagnosticsOfCleanFileDoNotWait(t *testing.T) {
+	f := goplstest.NewServer()
+	f.Respond("textDocument/diagnostic", map[string]any{"kind": "full", "items": []any{}})
+	cs, _ := connect(t, Options{Root: fakeRoot(t), GoplsDial: f.Dial})
+
+	start := time.Now()
+	var out tools.GetDiagnosticsOutput
+	callTool(t, cs, "get_diagnostics", map[string]any{"file_path": "main.go", "wait_ms": 10000}, &out)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %v: waited for a push although the pull reported a clean file", elapsed)
+	}
+	if len(out.Files) != 0 || out.ErrorCount != 0 {
+		t.Errorf("diagnostics = %+v, want none", out)
+	}
+}
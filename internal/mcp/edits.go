@@ -0,0 +1,72 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+// pendingEdit is the new content of one file changed by a WorkspaceEdit.
+type pendingEdit struct {
+	path    string
+	old     string
+	content string
+	edits   int
+	mode    os.FileMode
+}
+
+// prepareEdits computes the new content of every edited file without
+// touching the disk, so that an invalid edit leaves the workspace unchanged.
+func (s *session) prepareEdits(fileEdits []tools.FileEdits) ([]pendingEdit, error) {
+	roots := s.workspaceRoots()
+	var pending []pendingEdit
+	for _, fe := range fileEdits {
+		if len(fe.Edits) == 0 {
+			continue
+		}
+		if !inWorkspace(roots, fe.FilePath) {
+			return nil, fmt.Errorf("edit touches %s, which is outside the workspace", fe.FilePath)
+		}
+		info, err := os.Stat(fe.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		old, err := os.ReadFile(fe.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := tools.ApplyEdits(string(old), fe.Edits)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fe.FilePath, err)
+		}
+		pending = append(pending, pendingEdit{
+			path:    fe.FilePath,
+			old:     string(old),
+			content: updated,
+			edits:   len(fe.Edits),
+			mode:    info.Mode().Perm(),
+		})
+	}
+	return pending, nil
+}
+
+// writeEdits writes edited files to disk and tells gopls about the change.
+func (s *session) writeEdits(ctx context.Context, pending []pendingEdit) error {
+	var changes []map[string]any
+	for _, p := range pending {
+		if err := os.WriteFile(p.path, []byte(p.content), p.mode); err != nil {
+			return err
+		}
+		fileURI := pathToURI(p.path)
+		if _, err := s.docs.UpdateIfOpen(ctx, fileURI, p.content); err != nil {
+			return err
+		}
+		changes = append(changes, map[string]any{"uri": fileURI, "type": 2}) // FileChangeType.Changed
+	}
+	if len(changes) > 0 {
+		_ = s.client.SendNotification("workspace/didChangeWatchedFiles", map[string]any{"changes": changes})
+	}
+	return nil
+}
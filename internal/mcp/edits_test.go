@@ -0,0 +1,62 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dreamcats/bytelsp/internal/gopls/goplstest"
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+func TestApplyEditRefused(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f := goplstest.NewServer()
+	f.Respond("workspace/symbol", nil)
+	cs, _ := connect(t, Options{Root: root, GoplsDial: f.Dial})
+	var out tools.SearchSymbolsOutput
+	callTool(t, cs, "search_symbols", map[string]any{"query": "main", "no_index_wait": true}, &out)
+
+	var init struct {
+		Capabilities struct {
+			Workspace struct {
+				ApplyEdit bool `json:"applyEdit"`
+			} `json:"workspace"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(f.Received("initialize")[0], &init); err != nil {
+		t.Fatal(err)
+	}
+	if init.Capabilities.Workspace.ApplyEdit {
+		t.Error("client advertises workspace.applyEdit")
+	}
+
+	raw, err := f.Request(context.Background(), "workspace/applyEdit", map[string]any{
+		"edit": map[string]any{"changes": map[string]any{pathToURI(path): []map[string]any{{
+			"range":   map[string]any{"start": map[string]any{"line": 0, "character": 0}, "end": map[string]any{"line": 0, "character": 0}},
+			"newText": "// edited\n",
+		}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res struct {
+		Applied       bool   `json:"applied"`
+		FailureReason string `json:"failureReason"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Applied || res.FailureReason == "" {
+		t.Errorf("applyEdit = %s, want refused", raw)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "package main\n" {
+		t.Errorf("main.go changed to %q", data)
+	}
+}
@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"context"
+	"os"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+func (s *session) FindImplementations(ctx context.Context, _ *sdk.CallToolRequest, input tools.FindImplementationsInput) (*sdk.CallToolResult, tools.FindImplementationsOutput, error) {
+	if err := s.Initialize(ctx); err != nil {
+		return nil, tools.FindImplementationsOutput{}, err
+	}
+	notice := s.awaitWorkspaceLoad(ctx, input.NoIndexWait)
+	maxResults := input.MaxResults
+	if maxResults <= 0 {
+		maxResults = 50
+	}
+
+	target, err := s.resolveTarget(ctx, input.FilePath, input.Symbol, input.Line, input.Col, input.Offset)
+	if err != nil {
+		return nil, tools.FindImplementationsOutput{}, err
+	}
+	_, uri, err := s.prepareDocument(ctx, target.absPath)
+	if err != nil {
+		return nil, tools.FindImplementationsOutput{}, err
+	}
+	s.warmupDocument(ctx, uri)
+
+	kind := target.kind
+	if kind == "" {
+		kind = kindAtDefinition(tools.Location{FilePath: target.absPath, Line: target.line, Col: target.col})
+	}
+	output := tools.FindImplementationsOutput{
+		Name:            target.name,
+		Kind:            kind,
+		Direction:       "interfaces",
+		Implementations: []tools.ImplementationItem{},
+	}
+	if kind == "Interface" || isInterfaceMethod(target) {
+		output.Direction = "implementations"
+	}
+
+	// gopls answers textDocument/implementation in both directions: concrete
+	// types for an interface, and interfaces for a concrete type.
+	params := map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": target.line - 1, "character": target.col - 1},
+	}
+	raw, err := s.client.SendRequest(ctx, "textDocument/implementation", params)
+	if err != nil {
+		return nil, tools.FindImplementationsOutput{}, err
+	}
+	locs, err := tools.ParseLocations(raw)
+	if err != nil {
+		return nil, tools.FindImplementationsOutput{}, err
+	}
+
+	roots := s.workspaceRoots()
+	for _, loc := range locs {
+		if !input.IncludeExternal && !inWorkspace(roots, loc.FilePath) {
+			continue
+		}
+		output.Total++
+		if len(output.Implementations) >= maxResults {
+			continue
+		}
+		item := tools.ImplementationItem{
+			FilePath: loc.FilePath,
+			Line:     loc.Line,
+			Col:      loc.Col,
+			Snippet:  getLineContent(loc.FilePath, loc.Line),
+		}
+		if decl, ok := declarationAt(loc); ok {
+			item.Name = decl.Name
+			item.Kind = decl.Kind
+		} else if code, err := os.ReadFile(loc.FilePath); err == nil {
+			item.Name = tools.IdentifierAt(string(code), loc.Line, loc.Col)
+		}
+		output.Implementations = append(output.Implementations, item)
+	}
+	output.Notice = notice
+	return nil, output, nil
+}
+
+// isInterfaceMethod reports whether target names a method declared in an interface.
+func isInterfaceMethod(target *symbolTarget) bool {
+	d, ok := declarationAt(tools.Location{FilePath: target.absPath, Line: target.line, Col: target.col})
+	return ok && d.Member && d.Kind == "Method"
+}
@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dreamcats/bytelsp/internal/gopls"
+)
+
+// defaultIndexWait is how long workspace-wide tools wait for gopls to finish
+// its initial workspace load before answering with partial results.
+const defaultIndexWait = 10 * time.Second
+
+// awaitWorkspaceLoad blocks until gopls has loaded the workspace, for at most
+// s.opts.IndexWait, or not at all with noWait (the no_index_wait input). It
+// returns a notice when results may still be incomplete.
+func (s *session) awaitWorkspaceLoad(ctx context.Context, noWait bool) string {
+	loaded := s.client.WorkspaceLoaded()
+	if s.opts.IndexWait > 0 && !noWait {
+		waitCtx, cancel := context.WithTimeout(ctx, s.opts.IndexWait)
+		defer cancel()
+		select {
+		case <-loaded:
+			return ""
+		case <-waitCtx.Done():
+		}
+	} else {
+		select {
+		case <-loaded:
+			return ""
+		default:
+		}
+	}
+	return indexingNotice(s.client.ActiveProgress())
+}
+
+// indexingNotice describes the work gopls is still doing.
+func indexingNotice(active []gopls.Progress) string {
+	notice := "indexing in progress, results may be incomplete"
+	if len(active) == 0 {
+		return notice
+	}
+	p := active[0]
+	detail := p.Title
+	if p.Message != "" {
+		detail += ": " + p.Message
+	}
+	if p.Percentage >= 0 {
+		detail += fmt.Sprintf(" (%d%%)", p.Percentage)
+	}
+	return notice + " (" + detail + ")"
+}
+
+// loadingStatus summarizes the gopls workspace load for the about resource.
+func loadingStatus(client *gopls.Client) string {
+	select {
+	case <-client.WorkspaceLoaded():
+		return "workspace loaded"
+	default:
+		return indexingNotice(client.ActiveProgress())
+	}
+}
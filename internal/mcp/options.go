@@ -0,0 +1,126 @@
+package mcp
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/dreamcats/bytelsp/internal/gopls"
+)
+
+// Options configures a Service. Zero values select the defaults.
+type Options struct {
+	Root       string   // Workspace root; detected from the working directory when empty
+	ExtraRoots []string // Additional workspace folders sent to gopls
+
+	GoplsPath string
+	GoplsArgs []string // Extra flags for `gopls serve`
+	// GoplsSettings are passed through to gopls (build flags, env, analyzers, ...).
+	GoplsSettings *gopls.Settings
+	// GoplsRemote shares a gopls daemon with editors: "auto" or a daemon
+	// address (see gopls.Config.Remote). Empty runs a private gopls.
+	GoplsRemote    string
+	GoplsAutoStart bool // Start a daemon on GoplsRemote if none is listening
+	// GoplsDial replaces gopls with another LSP transport, such as a
+	// gopls.Replayer for offline runs.
+	GoplsDial func() (io.ReadWriteCloser, error)
+	// RecordPath records all traffic with gopls to this file (see gopls.Recorder).
+	// With GoplsDial or RecordPath set, all calls run in the Root workspace:
+	// neither other workspaces nor client roots get a session.
+	RecordPath string
+
+	InitTimeout    time.Duration // gopls initialize (20s)
+	RequestTimeout time.Duration // Each gopls request (none)
+	IndexWait      time.Duration // Wait for the initial workspace load (10s; negative: don't wait)
+	WarmupTimeout  time.Duration // Diagnostic pull before queries (2s)
+
+	// Tool calls on code outside the workspace get a gopls session of their
+	// own. At most MaxSessions run at once (4); the least recently used is
+	// shut down to make room, as is any left idle for SessionIdleTimeout (10m).
+	MaxSessions        int
+	SessionIdleTimeout time.Duration
+
+	MaxReferences  int // explain_symbol max_references default (10)
+	SourceMaxLines int // Lines of definition source returned (100)
+	SourceMaxChars int // Source and body truncation (2000)
+
+	EnabledTools  []string // Register only these tools (all when empty)
+	DisabledTools []string // Do not register these tools
+}
+
+// ToolNames lists every tool the service can register.
+var ToolNames = []string{
+	"search_symbols",
+	"explain_symbol",
+	"list_symbols",
+	"get_call_hierarchy",
+	"explain_import",
+	"describe_package",
+	"find_implementations",
+	"get_type_hierarchy",
+	"rename_symbol",
+	"get_diagnostics",
+	"analyze_code",
+}
+
+func (o *Options) applyDefaults() {
+	if o.InitTimeout <= 0 {
+		o.InitTimeout = 20 * time.Second
+	}
+	if o.IndexWait == 0 {
+		o.IndexWait = defaultIndexWait
+	}
+	if o.WarmupTimeout <= 0 {
+		o.WarmupTimeout = 2 * time.Second
+	}
+	if o.MaxSessions <= 0 {
+		o.MaxSessions = 4
+	}
+	if o.SessionIdleTimeout <= 0 {
+		o.SessionIdleTimeout = 10 * time.Minute
+	}
+	if o.MaxReferences <= 0 {
+		o.MaxReferences = 10
+	}
+	if o.SourceMaxLines <= 0 {
+		o.SourceMaxLines = 100
+	}
+	if o.SourceMaxChars <= 0 {
+		o.SourceMaxChars = 2000
+	}
+}
+
+func (o *Options) validateTools() error {
+	known := make(map[string]bool, len(ToolNames))
+	for _, name := range ToolNames {
+		known[name] = true
+	}
+	for _, name := range append(append([]string{}, o.EnabledTools...), o.DisabledTools...) {
+		if !known[name] {
+			return fmt.Errorf("unknown tool %q", name)
+		}
+	}
+	return nil
+}
+
+// removeDisabledTools unregisters the tools turned off by the options.
+func (s *Service) removeDisabledTools(server *sdk.Server) {
+	var remove []string
+	if len(s.opts.EnabledTools) > 0 {
+		enabled := make(map[string]bool, len(s.opts.EnabledTools))
+		for _, name := range s.opts.EnabledTools {
+			enabled[name] = true
+		}
+		for _, name := range ToolNames {
+			if !enabled[name] {
+				remove = append(remove, name)
+			}
+		}
+	}
+	remove = append(remove, s.opts.DisabledTools...)
+	if len(remove) > 0 {
+		server.RemoveTools(remove...)
+	}
+}
@@ -0,0 +1,258 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+func (s *session) ListSymbols(ctx context.Context, _ *sdk.CallToolRequest, input tools.ListSymbolsInput) (*sdk.CallToolResult, tools.ListSymbolsOutput, error) {
+	if input.FilePath == "" {
+		return nil, tools.ListSymbolsOutput{}, errors.New("file_path is required")
+	}
+	if err := s.Initialize(ctx); err != nil {
+		return nil, tools.ListSymbolsOutput{}, err
+	}
+	maxResults := input.MaxResults
+	if maxResults <= 0 {
+		maxResults = 100
+	}
+
+	absPath, err := s.resolveDiskPath(input.FilePath)
+	if err != nil {
+		return nil, tools.ListSymbolsOutput{}, err
+	}
+	code, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, tools.ListSymbolsOutput{}, err
+	}
+	_, uri, err := s.prepareDocument(ctx, absPath)
+	if err != nil {
+		return nil, tools.ListSymbolsOutput{}, err
+	}
+
+	params := map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+	}
+	raw, err := s.client.SendRequest(ctx, "textDocument/documentSymbol", params)
+	if err != nil {
+		return nil, tools.ListSymbolsOutput{}, err
+	}
+	symbols, err := tools.ParseDocumentSymbols(raw)
+	if err != nil {
+		return nil, tools.ListSymbolsOutput{}, err
+	}
+
+	ob := &outlineBuilder{lines: strings.Split(string(code), "\n"), includeBody: input.IncludeBody, maxChars: s.opts.SourceMaxChars}
+	outline := ob.build(symbols)
+
+	filter := kindFilter{include: input.IncludeKinds, exclude: input.ExcludeKinds}
+	output := tools.ListSymbolsOutput{FilePath: absPath, Symbols: []tools.OutlineSymbol{}}
+	emitted := 0
+	emit := func(sym tools.OutlineSymbol) {
+		output.TotalCount++
+		if emitted >= maxResults {
+			output.Truncated = true
+			return
+		}
+		emitted++
+		output.Symbols = append(output.Symbols, sym)
+	}
+
+	for _, sym := range outline {
+		children := sym.Children
+		sym.Children = nil
+		if filter.allows(sym.Kind) {
+			// Reserve the parent's slot first so its members cannot crowd it out.
+			output.TotalCount++
+			keep := emitted < maxResults
+			if keep {
+				emitted++
+			} else {
+				output.Truncated = true
+			}
+			for _, child := range children {
+				if !filter.allows(child.Kind) {
+					continue
+				}
+				output.TotalCount++
+				if !keep || emitted >= maxResults {
+					output.Truncated = true
+					continue
+				}
+				emitted++
+				sym.Children = append(sym.Children, child)
+			}
+			if keep {
+				output.Symbols = append(output.Symbols, sym)
+			}
+			continue
+		}
+		// The type itself is filtered out: promote matching members.
+		for _, child := range children {
+			if filter.allows(child.Kind) {
+				emit(memberAsSymbol(child, sym.Name))
+			}
+		}
+	}
+	return nil, output, nil
+}
+
+// outlineBuilder converts gopls document symbols into a two-level outline,
+// attaching methods (reported at top level as "(*T).M") to their receiver type.
+type outlineBuilder struct {
+	lines       []string
+	includeBody bool
+	maxChars    int // Body truncation
+}
+
+func (b *outlineBuilder) build(symbols []tools.LSPDocumentSymbol) []tools.OutlineSymbol {
+	var out []tools.OutlineSymbol
+	var methods []tools.LSPDocumentSymbol
+	for _, ds := range symbols {
+		if ds.Kind == 6 && strings.HasPrefix(ds.Name, "(") {
+			methods = append(methods, ds)
+			continue
+		}
+		sym := tools.OutlineSymbol{
+			Name:      ds.Name,
+			NamePath:  ds.Name,
+			Kind:      tools.SymbolKindToString(ds.Kind),
+			Line:      ds.SelectionRange.Start.Line + 1,
+			Col:       ds.SelectionRange.Start.Character + 1,
+			EndLine:   ds.EndLine(),
+			Signature: b.signature(ds),
+			Body:      b.body(ds),
+		}
+		sym.Children = b.members(ds.Children, ds.Name)
+		out = append(out, sym)
+	}
+
+	index := make(map[string]int, len(out))
+	for i, sym := range out {
+		index[sym.Name] = i
+	}
+	for _, ds := range methods {
+		path, err := tools.ParseSymbolPath(ds.Name)
+		name, receiver := ds.Name, ""
+		if err == nil {
+			name, receiver = path.Name, path.Receiver
+		}
+		member := tools.OutlineMember{
+			Name:      name,
+			NamePath:  receiver + "." + name,
+			Kind:      "Method",
+			Line:      ds.SelectionRange.Start.Line + 1,
+			Col:       ds.SelectionRange.Start.Character + 1,
+			EndLine:   ds.EndLine(),
+			Signature: b.signature(ds),
+			Body:      b.body(ds),
+		}
+		if i, ok := index[receiver]; ok && receiver != "" {
+			out[i].Children = append(out[i].Children, member)
+			continue
+		}
+		sym := memberAsSymbol(member, receiver)
+		out = append(out, sym)
+	}
+
+	for i := range out {
+		children := out[i].Children
+		sort.SliceStable(children, func(a, c int) bool { return children[a].Line < children[c].Line })
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Line < out[j].Line })
+	return out
+}
+
+// members flattens nested children (e.g. fields of embedded anonymous structs)
+// into one level, keeping the full name path.
+func (b *outlineBuilder) members(children []tools.LSPDocumentSymbol, prefix string) []tools.OutlineMember {
+	var out []tools.OutlineMember
+	for _, ds := range children {
+		path := prefix + "." + ds.Name
+		out = append(out, tools.OutlineMember{
+			Name:      ds.Name,
+			NamePath:  path,
+			Kind:      tools.SymbolKindToString(ds.Kind),
+			Line:      ds.SelectionRange.Start.Line + 1,
+			Col:       ds.SelectionRange.Start.Character + 1,
+			EndLine:   ds.EndLine(),
+			Signature: b.signature(ds),
+			Body:      b.body(ds),
+		})
+		out = append(out, b.members(ds.Children, path)...)
+	}
+	return out
+}
+
+// signature returns the first source line of a symbol without a trailing brace.
+func (b *outlineBuilder) signature(ds tools.LSPDocumentSymbol) string {
+	line := ds.StartLine()
+	if line < 1 || line > len(b.lines) {
+		return ds.Detail
+	}
+	sig := strings.TrimSpace(b.lines[line-1])
+	sig = strings.TrimSpace(strings.TrimSuffix(sig, "{"))
+	return sig
+}
+
+func (b *outlineBuilder) body(ds tools.LSPDocumentSymbol) string {
+	if !b.includeBody {
+		return ""
+	}
+	start, end := ds.StartLine(), ds.EndLine()
+	if start < 1 || start > len(b.lines) {
+		return ""
+	}
+	if end > len(b.lines) {
+		end = len(b.lines)
+	}
+	body := strings.Join(b.lines[start-1:end], "\n")
+	if len(body) > b.maxChars {
+		body = body[:b.maxChars] + "\n// ... (truncated)"
+	}
+	return body
+}
+
+func memberAsSymbol(m tools.OutlineMember, receiver string) tools.OutlineSymbol {
+	return tools.OutlineSymbol{
+		Name:      m.Name,
+		NamePath:  m.NamePath,
+		Kind:      m.Kind,
+		Line:      m.Line,
+		Col:       m.Col,
+		EndLine:   m.EndLine,
+		Signature: m.Signature,
+		Receiver:  receiver,
+		Body:      m.Body,
+	}
+}
+
+// kindFilter matches symbol kinds case-insensitively.
+type kindFilter struct {
+	include []string
+	exclude []string
+}
+
+func (f kindFilter) allows(kind string) bool {
+	for _, k := range f.exclude {
+		if strings.EqualFold(k, kind) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, k := range f.include {
+		if strings.EqualFold(k, kind) {
+			return true
+		}
+	}
+	return false
+}
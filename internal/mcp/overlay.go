@@ -0,0 +1,121 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dreamcats/bytelsp/internal/gopls"
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+// snippetName is the base name of the file a snippet is checked as.
+const snippetName = "mcp_snippet"
+
+// openOverlay shows content to gopls as the unsaved buffer of path, at the
+// returned document version; nothing is written to disk. The returned func
+// closes the buffer again (once no call uses it), so gopls goes back to the
+// file on disk, or forgets path if it does not exist. It fails while another
+// call is querying path.
+func (s *session) openOverlay(ctx context.Context, path, content string) (string, int, func(), error) {
+	uri := pathToURI(path)
+	version, err := s.docs.OpenOrUpdate(ctx, uri, "go", content)
+	if errors.Is(err, gopls.ErrInUse) {
+		return "", 0, nil, fmt.Errorf("%s is in use by another call in progress; try again when it is done", path)
+	}
+	if err != nil {
+		return "", 0, nil, err
+	}
+	release := func() {
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.opts.WarmupTimeout)
+		defer cancel()
+		_ = s.docs.Close(closeCtx, uri)
+		s.diagnostics.Forget(uri)
+	}
+	return uri, version, release, nil
+}
+
+// resolveOverlayPath resolves the file a snippet stands for. The file need
+// not exist, but its directory must.
+func (s *session) resolveOverlayPath(filePath string) (string, error) {
+	cleaned := filepath.Clean(filePath)
+	if cleaned == "" || cleaned == "." {
+		return "", errors.New("file_path cannot be empty")
+	}
+	if !strings.HasSuffix(cleaned, ".go") {
+		return "", fmt.Errorf("file_path %s is not a .go file", filePath)
+	}
+	if !filepath.IsAbs(cleaned) {
+		if cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(os.PathSeparator)) {
+			return "", errors.New("file_path escapes workspace; use absolute path")
+		}
+		cleaned = filepath.Join(s.root, cleaned)
+	}
+	if info, err := os.Stat(filepath.Dir(cleaned)); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("directory of %s does not exist", cleaned)
+	}
+	return cleaned, nil
+}
+
+// snippetPath reserves a path in the directory of pkg (a package directory
+// or import path) that neither a file on disk nor another call in progress
+// uses, for checking a snippet as an additional file of that package. The
+// returned func gives the path up again.
+func (s *session) snippetPath(pkg string) (string, func(), error) {
+	dir, err := s.resolvePackageDir(pkg)
+	if err != nil {
+		return "", nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; ; i++ {
+		name := snippetName + ".go"
+		if i > 0 {
+			name = fmt.Sprintf("%s_%d.go", snippetName, i)
+		}
+		path := filepath.Join(dir, name)
+		if s.snippets[path] {
+			continue
+		}
+		_, err := os.Stat(path)
+		if err == nil {
+			continue
+		}
+		if !os.IsNotExist(err) {
+			return "", nil, err
+		}
+		if s.snippets == nil {
+			s.snippets = make(map[string]bool)
+		}
+		s.snippets[path] = true
+		release := func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.snippets, path)
+		}
+		return path, release, nil
+	}
+}
+
+// resolvePackageDir returns the directory of pkg, a package directory
+// (absolute or workspace-relative) or an import path.
+func (s *session) resolvePackageDir(pkg string) (string, error) {
+	dir := pkg
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(s.root, pkg)
+	}
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return dir, nil
+	}
+	info, err := tools.ResolveImportPath(s.root, pkg)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve package: %w", err)
+	}
+	if info.Dir == "" {
+		return "", fmt.Errorf("package %s has no directory", pkg)
+	}
+	return info.Dir, nil
+}
@@ -0,0 +1,43 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+func (s *session) DescribePackage(ctx context.Context, _ *sdk.CallToolRequest, input tools.DescribePackageInput) (*sdk.CallToolResult, tools.DescribePackageOutput, error) {
+	if input.Package == "" {
+		return nil, tools.DescribePackageOutput{}, errors.New("package is required")
+	}
+
+	// Directories are passed to go list as absolute paths; anything else is an import path.
+	target := input.Package
+	dir := target
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(s.root, dir)
+	}
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		target = dir
+	}
+
+	pkg, err := tools.ResolveImportPath(s.root, target)
+	if err != nil {
+		return nil, tools.DescribePackageOutput{}, fmt.Errorf("failed to resolve package: %w", err)
+	}
+	if pkg.Dir == "" {
+		return nil, tools.DescribePackageOutput{}, fmt.Errorf("package %s has no source directory", input.Package)
+	}
+
+	result, err := tools.DescribePackage(pkg, input.Compact)
+	if err != nil {
+		return nil, tools.DescribePackageOutput{}, err
+	}
+	return nil, *result, nil
+}
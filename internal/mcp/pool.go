@@ -0,0 +1,197 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/dreamcats/bytelsp/internal/gopls"
+	"github.com/dreamcats/bytelsp/internal/workspace"
+)
+
+// targeted is implemented by tool inputs that can name a workspace or an
+// absolute file or package path (see tools.SearchSymbolsInput.Target).
+type targeted interface {
+	Target() (workspace, path string)
+}
+
+// bind adapts a session method to a tool handler. The call runs against
+// the session of the workspace it targets, the current one by default.
+func bind[In, Out any](s *Service, h func(*session, context.Context, *sdk.CallToolRequest, In) (*sdk.CallToolResult, Out, error)) sdk.ToolHandlerFor[In, Out] {
+	return func(ctx context.Context, req *sdk.CallToolRequest, input In) (*sdk.CallToolResult, Out, error) {
+		s.waitRoots(ctx)
+		var dir, path string
+		if t, ok := any(input).(targeted); ok {
+			dir, path = t.Target()
+		}
+		ws, err := s.acquire(dir, path)
+		if err != nil {
+			var zero Out
+			return nil, zero, err
+		}
+		defer s.release(ws)
+		// Keep the documents this call opens from being evicted under it.
+		ctx, unpin := gopls.PinDocuments(ctx)
+		defer unpin()
+		return h(ws, ctx, req, input)
+	}
+}
+
+// session returns the session of the current workspace.
+func (s *Service) session() *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current
+}
+
+// acquire returns the session for a call naming workspace dir and path,
+// starting one if needed; release it when the call is done. Without a
+// workspace, an absolute path outside the current workspace selects the
+// workspace around it.
+func (s *Service) acquire(dir, path string) (*session, error) {
+	root, err := s.targetRoot(dir, path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errors.New("service closed")
+	}
+	ws := s.current
+	if root != "" && root != ws.root && !s.singleSession() {
+		if ws = s.sessions[root]; ws == nil {
+			if ws, err = newSession(root, nil, s.opts, s.recorder); err != nil {
+				return nil, err
+			}
+			s.sessions[root] = ws
+			s.startReaper()
+		}
+	}
+	ws.active++
+	ws.lastUsed = time.Now()
+	s.evict()
+	return ws, nil
+}
+
+// singleSession reports whether every call runs in the current session: a
+// recording or replay covers one gopls connection and one workspace root,
+// so other workspaces get no sessions of their own.
+func (s *Service) singleSession() bool {
+	return s.opts.RecordPath != "" || s.opts.GoplsDial != nil
+}
+
+func (s *Service) release(ws *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ws.active--
+	ws.lastUsed = time.Now()
+	s.evict()
+}
+
+// targetRoot returns the workspace root a call targets, or "" for the
+// current workspace.
+func (s *Service) targetRoot(dir, path string) (string, error) {
+	if dir != "" {
+		if !filepath.IsAbs(dir) {
+			return "", fmt.Errorf("workspace %q must be an absolute path", dir)
+		}
+		if err := workspace.ValidateRoot(dir); err != nil {
+			return "", fmt.Errorf("workspace: %w", err)
+		}
+		return workspace.DetectRoot(dir)
+	}
+	if path == "" || !filepath.IsAbs(path) || inWorkspace(s.session().workspaceRoots(), path) {
+		return "", nil
+	}
+	start := path
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		start = filepath.Dir(path)
+	}
+	if _, err := os.Stat(start); err != nil {
+		return "", nil // Let the tool report the missing file
+	}
+	return workspace.DetectRoot(start)
+}
+
+// evict shuts down the least recently used idle sessions beyond
+// MaxSessions. The current session is never evicted. Called with s.mu held.
+func (s *Service) evict() {
+	for len(s.sessions) > s.opts.MaxSessions {
+		var victim *session
+		for _, ws := range s.sessions {
+			if ws == s.current || ws.active > 0 {
+				continue
+			}
+			if victim == nil || ws.lastUsed.Before(victim.lastUsed) {
+				victim = ws
+			}
+		}
+		if victim == nil {
+			return // Everything is busy; shrink once calls finish
+		}
+		s.drop(victim, "evicted")
+	}
+}
+
+// drop removes a session from the pool and shuts its gopls down. Called
+// with s.mu held.
+func (s *Service) drop(ws *session, reason string) {
+	delete(s.sessions, ws.root)
+	if ws.started() != nil {
+		log.Printf("gopls session %s %s", ws.root, reason)
+	}
+	go ws.close()
+}
+
+// startReaper starts shutting down idle sessions, once. Called with s.mu held.
+func (s *Service) startReaper() {
+	if s.reaping {
+		return
+	}
+	s.reaping = true
+	interval := s.opts.SessionIdleTimeout / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+			}
+			s.mu.Lock()
+			for _, ws := range s.sessions {
+				if ws != s.current && ws.active == 0 && time.Since(ws.lastUsed) >= s.opts.SessionIdleTimeout {
+					s.drop(ws, "shut down after being idle")
+				}
+			}
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// sessionRoots lists the roots of the pooled sessions, current first.
+func (s *Service) sessionRoots() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	roots := make([]string, 0, len(s.sessions))
+	for root := range s.sessions {
+		if root != s.current.root {
+			roots = append(roots, root)
+		}
+	}
+	sort.Strings(roots)
+	return append([]string{s.current.root}, roots...)
+}
@@ -0,0 +1,140 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// newPool starts a Service in a module "current" next to modules a, b and
+// c, and returns it with the base directory. gopls never starts: sessions
+// only start it on first use.
+func newPool(t *testing.T, opts Options) (*Service, string) {
+	t.Helper()
+	base := t.TempDir()
+	for _, name := range []string{"current", "a", "b", "c"} {
+		dir := filepath.Join(base, name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/"+name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts.Root = filepath.Join(base, "current")
+	svc, err := NewService(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = svc.Close() })
+	return svc, base
+}
+
+// pooled returns the base names of the pooled session roots, sorted.
+func pooled(svc *Service) []string {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	var names []string
+	for root := range svc.sessions {
+		names = append(names, filepath.Base(root))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func assertPooled(t *testing.T, svc *Service, want ...string) {
+	t.Helper()
+	got := pooled(svc)
+	sort.Strings(want)
+	if len(got) != len(want) {
+		t.Fatalf("sessions = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("sessions = %v, want %v", got, want)
+		}
+	}
+}
+
+// use runs one call in the workspace dir.
+func use(t *testing.T, svc *Service, dir string) {
+	t.Helper()
+	ws, err := svc.acquire(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.release(ws)
+}
+
+func TestPoolEvictsLeastRecentlyUsed(t *testing.T) {
+	svc, base := newPool(t, Options{MaxSessions: 3})
+	use(t, svc, filepath.Join(base, "a"))
+	use(t, svc, filepath.Join(base, "b"))
+	assertPooled(t, svc, "current", "a", "b")
+
+	use(t, svc, filepath.Join(base, "a"))
+	use(t, svc, filepath.Join(base, "c"))
+	assertPooled(t, svc, "current", "a", "c")
+}
+
+func TestPoolKeepsCurrentAndBusySessions(t *testing.T) {
+	svc, base := newPool(t, Options{MaxSessions: 1})
+	use(t, svc, filepath.Join(base, "a"))
+	assertPooled(t, svc, "current")
+
+	busy, err := svc.acquire(filepath.Join(base, "a"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	use(t, svc, filepath.Join(base, "b"))
+	assertPooled(t, svc, "current", "a")
+
+	svc.release(busy)
+	assertPooled(t, svc, "current")
+}
+
+func TestPoolShutsDownIdleSessions(t *testing.T) {
+	svc, base := newPool(t, Options{SessionIdleTimeout: 20 * time.Millisecond})
+	use(t, svc, filepath.Join(base, "a"))
+	deadline := time.Now().Add(5 * time.Second)
+	for len(pooled(svc)) > 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("sessions = %v, want the idle one shut down", pooled(svc))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assertPooled(t, svc, "current")
+}
+
+func TestTargetRootFromPath(t *testing.T) {
+	svc, base := newPool(t, Options{})
+	for _, tt := range []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(base, "a", "main.go"), filepath.Join(base, "a")},
+		{filepath.Join(base, "b"), filepath.Join(base, "b")},
+		{filepath.Join(base, "current", "main.go"), ""},
+		{"main.go", ""},
+		{filepath.Join(base, "missing", "main.go"), ""},
+	} {
+		got, err := svc.targetRoot("", tt.path)
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+		} else if got != tt.want {
+			t.Errorf("%s: root = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	ws, err := svc.acquire("", filepath.Join(base, "a", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.release(ws)
+	if ws.root != filepath.Join(base, "a") {
+		t.Errorf("session root = %s, want module a", ws.root)
+	}
+}
@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"path/filepath"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+func (s *session) RenameSymbol(ctx context.Context, _ *sdk.CallToolRequest, input tools.RenameSymbolInput) (*sdk.CallToolResult, tools.RenameSymbolOutput, error) {
+	if input.NewName == "" {
+		return nil, tools.RenameSymbolOutput{}, errors.New("new_name is required")
+	}
+	if !token.IsIdentifier(input.NewName) {
+		return nil, tools.RenameSymbolOutput{}, fmt.Errorf("new_name %q is not a valid Go identifier", input.NewName)
+	}
+	if err := s.Initialize(ctx); err != nil {
+		return nil, tools.RenameSymbolOutput{}, err
+	}
+
+	target, err := s.resolveTarget(ctx, input.FilePath, input.Symbol, input.Line, input.Col, input.Offset)
+	if err != nil {
+		return nil, tools.RenameSymbolOutput{}, err
+	}
+	rootAbs, err := filepath.Abs(s.root)
+	if err != nil {
+		rootAbs = s.root
+	}
+	if !inWorkspace(s.workspaceRoots(), target.absPath) {
+		return nil, tools.RenameSymbolOutput{}, fmt.Errorf("%s is outside the workspace", target.absPath)
+	}
+	_, uri, err := s.prepareDocument(ctx, target.absPath)
+	if err != nil {
+		return nil, tools.RenameSymbolOutput{}, err
+	}
+	s.warmupDocument(ctx, uri)
+
+	position := map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": target.line - 1, "character": target.col - 1},
+	}
+	raw, err := s.client.SendRequest(ctx, "textDocument/prepareRename", position)
+	if err != nil {
+		return nil, tools.RenameSymbolOutput{}, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, tools.RenameSymbolOutput{}, fmt.Errorf("%q cannot be renamed at %d:%d", target.name, target.line, target.col)
+	}
+	oldName := target.name
+	var prepared struct {
+		Placeholder string `json:"placeholder"`
+	}
+	if err := json.Unmarshal(raw, &prepared); err == nil && prepared.Placeholder != "" {
+		oldName = prepared.Placeholder
+	}
+	if oldName == input.NewName {
+		return nil, tools.RenameSymbolOutput{}, fmt.Errorf("symbol is already named %q", input.NewName)
+	}
+
+	position["newName"] = input.NewName
+	raw, err = s.client.SendRequest(ctx, "textDocument/rename", position)
+	if err != nil {
+		return nil, tools.RenameSymbolOutput{}, err
+	}
+	fileEdits, err := tools.ParseWorkspaceEdit(raw)
+	if err != nil {
+		return nil, tools.RenameSymbolOutput{}, err
+	}
+
+	pending, err := s.prepareEdits(fileEdits)
+	if err != nil {
+		return nil, tools.RenameSymbolOutput{}, err
+	}
+	output := tools.RenameSymbolOutput{OldName: oldName, NewName: input.NewName, Files: []tools.RenameFileChange{}}
+	for _, p := range pending {
+		rel, err := filepath.Rel(rootAbs, p.path)
+		if err != nil {
+			rel = p.path
+		}
+		output.Files = append(output.Files, tools.RenameFileChange{
+			FilePath: p.path,
+			Edits:    p.edits,
+			Diff:     tools.UnifiedDiff(filepath.ToSlash(rel), p.old, p.content),
+		})
+		output.EditCount += p.edits
+	}
+	output.FileCount = len(output.Files)
+	if !input.Apply {
+		return nil, output, nil
+	}
+
+	if err := s.writeEdits(ctx, pending); err != nil {
+		return nil, tools.RenameSymbolOutput{}, err
+	}
+	output.Applied = true
+	return nil, output, nil
+}
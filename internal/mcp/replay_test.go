@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/dreamcats/bytelsp/internal/gopls"
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+var update = flag.Bool("update", false, "re-record "+recording+" with gopls from PATH")
+
+// recording holds the gopls traffic of replayCalls on testdata/replay.
+const recording = "testdata/replay.jsonl"
+
+// TestReplay runs tools against a recorded gopls session, checking the
+// request matching of gopls.Replayer and the parsing of gopls responses.
+func TestReplay(t *testing.T) {
+	root, err := filepath.Abs("testdata/replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		cs, svc := connect(t, Options{Root: root, RecordPath: recording})
+		replayCalls(t, cs)
+		if err := svc.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replayer, err := gopls.NewReplayer(recording, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, _ := connect(t, Options{Root: root, GoplsDial: replayer.Dial})
+	explain, calls := replayCalls(t, cs)
+
+	if explain.Kind != "Method" {
+		t.Errorf("kind = %q, want Method", explain.Kind)
+	}
+	if explain.Signature != "func (g *Greeter) Greet() string" {
+		t.Errorf("signature = %q", explain.Signature)
+	}
+	if !strings.HasPrefix(explain.Doc, "Greet returns the greeting for g.") {
+		t.Errorf("doc = %q", explain.Doc)
+	}
+	want := tools.Location{FilePath: filepath.Join(root, "main.go"), Line: 11, Col: 19, EndLine: 11, EndCol: 24}
+	if explain.DefinedAt == nil || *explain.DefinedAt != want {
+		t.Errorf("defined_at = %+v, want %+v", explain.DefinedAt, want)
+	}
+	if !strings.Contains(explain.Source, "return hello(g.Name)") {
+		t.Errorf("source = %q", explain.Source)
+	}
+	if explain.ReferencesCount != 1 || len(explain.References) != 1 ||
+		explain.References[0].Line != 21 || !strings.Contains(explain.References[0].Context, "g.Greet()") {
+		t.Errorf("references = %d %+v", explain.ReferencesCount, explain.References)
+	}
+
+	if calls.Name != "hello" || calls.Kind != "Function" || calls.Line != 15 {
+		t.Errorf("call hierarchy item = %s %s line %d", calls.Name, calls.Kind, calls.Line)
+	}
+	if len(calls.Incoming) != 1 || calls.Incoming[0].Name != "Greet" ||
+		calls.Incoming[0].Line != 11 || !strings.Contains(calls.Incoming[0].Context, "Greet() string") {
+		t.Errorf("incoming = %+v", calls.Incoming)
+	}
+	if len(calls.Outgoing) != 1 || calls.Outgoing[0].Name != "Sprintf" {
+		t.Errorf("outgoing = %+v", calls.Outgoing)
+	}
+}
+
+// replayCalls makes the tool calls the recording was made from.
+func replayCalls(t *testing.T, cs *sdk.ClientSession) (tools.ExplainSymbolOutput, tools.GetCallHierarchyOutput) {
+	t.Helper()
+	var explain tools.ExplainSymbolOutput
+	callTool(t, cs, "explain_symbol", map[string]any{"file_path": "main.go", "symbol": "Greet"}, &explain)
+	var calls tools.GetCallHierarchyOutput
+	callTool(t, cs, "get_call_hierarchy", map[string]any{"file_path": "main.go", "symbol": "hello"}, &calls)
+	return explain, calls
+}
+
+// connect serves a Service over in-memory transports and returns a client
+// session for it.
+func connect(t *testing.T, opts Options) (*sdk.ClientSession, *Service) {
+	t.Helper()
+	ctx := context.Background()
+	svc, err := NewService(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = svc.Close() })
+	server := sdk.NewServer(&sdk.Implementation{Name: "byte-lsp-mcp"}, svc.ServerOptions())
+	svc.Register(server)
+	serverTransport, clientTransport := sdk.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatal(err)
+	}
+	cs, err := sdk.NewClient(&sdk.Implementation{Name: "test"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cs.Close() })
+	return cs, svc
+}
+
+// callTool calls a tool and decodes its structured result into out.
+func callTool(t *testing.T, cs *sdk.ClientSession, name string, args map[string]any, out any) {
+	t.Helper()
+	res, err := cs.CallTool(context.Background(), &sdk.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if res.IsError {
+		t.Fatalf("%s: %s", name, res.Content[0].(*sdk.TextContent).Text)
+	}
+	data, err := json.Marshal(res.StructuredContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+}
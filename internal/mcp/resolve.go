@@ -0,0 +1,192 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+// symbolTarget is a resolved position that LSP requests can be issued against.
+type symbolTarget struct {
+	absPath string
+	code    string
+	line    int // 1-based
+	col     int // 1-based
+	name    string
+	kind    string // Empty when only a textual match was found
+}
+
+// resolveTarget locates the identifier a tool call refers to, either by an
+// explicit position (line/col or byte offset) or by symbol name.
+func (s *session) resolveTarget(ctx context.Context, filePath, symbol string, line, col int, offset *int) (*symbolTarget, error) {
+	if line <= 0 && offset == nil {
+		if symbol == "" {
+			return nil, errors.New("symbol or line/col (or offset) is required")
+		}
+		return s.resolveSymbol(ctx, filePath, symbol)
+	}
+	if filePath == "" {
+		return nil, errors.New("file_path is required with line/col or offset")
+	}
+
+	absPath, err := s.resolveDiskPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	code, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	if line <= 0 {
+		var ok bool
+		line, col, ok = tools.OffsetToPosition(string(code), *offset)
+		if !ok {
+			return nil, fmt.Errorf("offset %d is outside the file (size %d)", *offset, len(code))
+		}
+	} else if col <= 0 {
+		return nil, errors.New("col is required with line")
+	}
+	lineCount := strings.Count(string(code), "\n") + 1
+	if line > lineCount {
+		return nil, fmt.Errorf("line %d is outside the file (%d lines)", line, lineCount)
+	}
+
+	name := tools.IdentifierAt(string(code), line, col)
+	if name == "" {
+		return nil, fmt.Errorf("no identifier at %d:%d", line, col)
+	}
+	return &symbolTarget{absPath: absPath, code: string(code), line: line, col: col, name: name}, nil
+}
+
+// declarationAt returns the declaration whose name starts at loc, if any.
+func declarationAt(loc tools.Location) (tools.SymbolCandidate, bool) {
+	code, err := os.ReadFile(loc.FilePath)
+	if err != nil {
+		return tools.SymbolCandidate{}, false
+	}
+	decls, err := tools.DeclarationsInFile(string(code), loc.FilePath)
+	if err != nil {
+		return tools.SymbolCandidate{}, false
+	}
+	for _, d := range decls {
+		if d.Line == loc.Line && d.Col == loc.Col {
+			return d, true
+		}
+	}
+	return tools.SymbolCandidate{}, false
+}
+
+// kindAtDefinition returns the declaration kind at a definition location,
+// or "" when the definition is not a package-level declaration, field or method.
+func kindAtDefinition(loc tools.Location) string {
+	if d, ok := declarationAt(loc); ok {
+		return d.Kind
+	}
+	return ""
+}
+
+// resolveSymbol locates symbol, which may be a bare identifier or a qualified
+// name path (Type.Method, (*Type).Method, pkg.Type.Method, import/path.Name).
+// Declarations in filePath are tried first; qualified names that are not
+// declared there, and import path references, which a single file cannot
+// check, are looked up through workspace/symbol.
+func (s *session) resolveSymbol(ctx context.Context, filePath, symbol string) (*symbolTarget, error) {
+	path, err := tools.ParseSymbolPath(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if filePath == "" && !path.Qualified() {
+		return nil, errors.New("file_path is required unless symbol is qualified (e.g. Type.Method or pkg.Name)")
+	}
+
+	if filePath != "" && path.ImportPath == "" {
+		absPath, err := s.resolveDiskPath(filePath)
+		if err != nil {
+			return nil, err
+		}
+		code, err := os.ReadFile(absPath)
+		if err != nil {
+			return nil, err
+		}
+		candidates, err := tools.FindSymbolCandidates(string(code), absPath, path)
+		if err == nil {
+			switch {
+			case len(candidates) == 1:
+				c := candidates[0]
+				return &symbolTarget{absPath: absPath, code: string(code), line: c.Line, col: c.Col, name: c.Ident, kind: c.Kind}, nil
+			case len(candidates) > 1:
+				return nil, &tools.AmbiguousSymbolError{Symbol: symbol, Candidates: candidates}
+			}
+		}
+		if !path.Qualified() {
+			// Not declared here: fall back to the first use of the identifier,
+			// e.g. a call to an imported function.
+			line, col, ok := tools.FindSymbolPosition(string(code), path.Name)
+			if !ok {
+				return nil, errors.New("symbol not found in file")
+			}
+			return &symbolTarget{absPath: absPath, code: string(code), line: line, col: col, name: path.Name}, nil
+		}
+	}
+
+	return s.resolveWorkspaceSymbol(ctx, path)
+}
+
+// resolveWorkspaceSymbol finds a qualified symbol anywhere in the workspace
+// (or in dependencies when an import path is given).
+func (s *session) resolveWorkspaceSymbol(ctx context.Context, path tools.SymbolPath) (*symbolTarget, error) {
+	raw, err := s.client.SendRequest(ctx, "workspace/symbol", map[string]interface{}{"query": path.Name})
+	if err != nil {
+		return nil, err
+	}
+	items, err := tools.ParseSymbols(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := s.workspaceRoots()
+	var inside, outside []tools.SymbolInformation
+	for _, item := range items {
+		if item.FilePath == "" || !path.MatchesWorkspaceSymbol(item) {
+			continue
+		}
+		if inWorkspace(roots, item.FilePath) {
+			inside = append(inside, item)
+		} else {
+			outside = append(outside, item)
+		}
+	}
+	matches := inside
+	if len(matches) == 0 && path.ImportPath != "" {
+		matches = outside
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("symbol %q not found in workspace", path.Raw)
+	case 1:
+	default:
+		candidates := make([]tools.SymbolCandidate, 0, len(matches))
+		for _, m := range matches {
+			candidates = append(candidates, tools.SymbolCandidate{
+				Name:     m.ContainerName + "." + m.Name,
+				Kind:     m.Kind,
+				FilePath: m.FilePath,
+				Line:     m.Line,
+				Col:      m.Col,
+			})
+		}
+		return nil, &tools.AmbiguousSymbolError{Symbol: path.Raw, Candidates: candidates}
+	}
+
+	m := matches[0]
+	code, err := os.ReadFile(m.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	return &symbolTarget{absPath: m.FilePath, code: string(code), line: m.Line, col: m.Col, name: path.Name, kind: m.Kind}, nil
+}
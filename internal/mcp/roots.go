@@ -0,0 +1,149 @@
+package mcp
+
+import (
+	"context"
+	"log"
+	"time"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/dreamcats/bytelsp/internal/tools"
+	"github.com/dreamcats/bytelsp/internal/workspace"
+)
+
+const (
+	// How long a roots/list request to the MCP client may take.
+	rootsTimeout = 5 * time.Second
+	// How long a tool call waits for the first roots/list answer before
+	// using the workspace detected from the working directory.
+	rootsWait = 5 * time.Second
+)
+
+func (s *Service) waitRoots(ctx context.Context) {
+	s.mu.Lock()
+	synced := s.rootsSynced
+	s.mu.Unlock()
+	select {
+	case <-synced:
+	case <-ctx.Done():
+	case <-time.After(rootsWait):
+	}
+}
+
+// ServerOptions returns the MCP server options the service relies on to
+// follow the client's roots. Pass them to sdk.NewServer.
+func (s *Service) ServerOptions() *sdk.ServerOptions {
+	return &sdk.ServerOptions{
+		InitializedHandler: func(ctx context.Context, req *sdk.InitializedRequest) {
+			if !s.usesClientRoots(req.Session) {
+				return
+			}
+			synced := make(chan struct{})
+			s.mu.Lock()
+			s.rootsSynced = synced
+			s.mu.Unlock()
+			// Requests to the client cannot be answered while its
+			// notification is being handled.
+			go func() {
+				defer close(synced)
+				s.syncRoots(req.Session)
+			}()
+		},
+		RootsListChangedHandler: func(ctx context.Context, req *sdk.RootsListChangedRequest) {
+			if s.usesClientRoots(req.Session) {
+				go s.syncRoots(req.Session)
+			}
+		},
+	}
+}
+
+// usesClientRoots reports whether the workspace follows the client's roots:
+// the client must support them, no root may be configured explicitly and
+// gopls traffic may not be recorded or replayed (see singleSession).
+func (s *Service) usesClientRoots(ss *sdk.ServerSession) bool {
+	if s.opts.Root != "" || s.singleSession() {
+		return false
+	}
+	params := ss.InitializeParams()
+	return params != nil && params.Capabilities != nil && params.Capabilities.RootsV2 != nil
+}
+
+// syncRoots asks the client for its roots and moves the workspace there.
+// Each root maps to a workspace root via its go.work or go.mod; roots that
+// are not directories are skipped. If one of them is the current workspace
+// root, it stays, the client roots become its workspace folders and gopls
+// is told about the change. Otherwise the first root's workspace becomes
+// the current one, reusing its pooled session if there is one.
+func (s *Service) syncRoots(ss *sdk.ServerSession) {
+	ctx, cancel := context.WithTimeout(context.Background(), rootsTimeout)
+	defer cancel()
+	res, err := ss.ListRoots(ctx, &sdk.ListRootsParams{})
+	if err != nil {
+		log.Printf("roots/list: %v", err)
+		return
+	}
+	var dirs []string
+	for _, r := range res.Roots {
+		if path := tools.URIToPath(r.URI); path != r.URI {
+			dirs = append(dirs, path)
+		}
+	}
+	if len(dirs) == 0 {
+		return // Keep the current workspace
+	}
+	s.mu.Lock()
+	current := s.current
+	s.mu.Unlock()
+
+	root := ""
+	var valid []string
+	for _, dir := range dirs {
+		err := workspace.ValidateRoot(dir)
+		var detected string
+		if err == nil {
+			detected, err = workspace.DetectRoot(dir)
+		}
+		if err != nil {
+			log.Printf("roots: skipping %s: %v", dir, err)
+			continue
+		}
+		valid = append(valid, dir)
+		if root == "" || detected == current.root {
+			root = detected
+		}
+	}
+	if root == "" {
+		return // Keep the current workspace
+	}
+	extra := append(valid, s.opts.ExtraRoots...)
+	if root == current.root {
+		folders, err := workspace.Folders(root, extra)
+		if err == nil {
+			err = current.setFolders(folders)
+		}
+		if err != nil {
+			log.Printf("roots: %v", err)
+		}
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	next := s.sessions[root]
+	if next == nil {
+		if next, err = newSession(root, extra, s.opts, s.recorder); err != nil {
+			log.Printf("roots: %v", err)
+			return
+		}
+		s.sessions[root] = next
+	} else if folders, err := workspace.Folders(root, extra); err == nil {
+		// Notify gopls without holding s.mu.
+		go next.setFolders(folders)
+	}
+	// The previous workspace stays pooled until evicted or idle.
+	s.current = next
+	s.startReaper()
+	s.evict()
+}
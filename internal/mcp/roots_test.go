@@ -0,0 +1,100 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/dreamcats/bytelsp/internal/gopls/goplstest"
+)
+
+func TestSyncRootsSkipsInvalidRoots(t *testing.T) {
+	root, err := filepath.Abs("testdata/replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	svc, err := NewService(ctx, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = svc.Close() })
+	server := sdk.NewServer(&sdk.Implementation{Name: "byte-lsp-mcp"}, svc.ServerOptions())
+	svc.Register(server)
+	serverTransport, clientTransport := sdk.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatal(err)
+	}
+	client := sdk.NewClient(&sdk.Implementation{Name: "test"}, nil)
+	client.AddRoots(
+		&sdk.Root{URI: fileURI(filepath.Join(t.TempDir(), "missing"))},
+		&sdk.Root{URI: fileURI(root)},
+	)
+	cs, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cs.Close() })
+
+	// The roots are fetched once the client's initialized notification has
+	// been handled, which Connect does not wait for.
+	deadline := time.Now().Add(5 * time.Second)
+	for svc.session().root != root {
+		if time.Now().After(deadline) {
+			t.Fatalf("workspace root = %s, want %s", svc.session().root, root)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+func TestFoldersChangedDuringInitialize(t *testing.T) {
+	root := fakeRoot(t)
+	extra := t.TempDir()
+	f := goplstest.NewServer()
+	f.Delay("initialize", 200*time.Millisecond)
+	s, err := newSession(root, nil, Options{GoplsDial: f.Dial, InitTimeout: 5 * time.Second}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.close() })
+
+	done := make(chan error, 1)
+	go func() { done <- s.Initialize(context.Background()) }()
+	waitFor(t, "initialize", func() bool { return len(f.Received("initialize")) == 1 })
+	if err := s.setFolders([]string{root, extra}); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(f.Received("workspace/didChangeWorkspaceFolders")); got != 0 {
+		t.Fatal("folder change sent before initialize was answered")
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// The change is sent once gopls is initialized.
+	waitFor(t, "didChangeWorkspaceFolders", func() bool {
+		return len(f.Received("workspace/didChangeWorkspaceFolders")) == 1
+	})
+	var change struct {
+		Event struct {
+			Added []struct {
+				URI string `json:"uri"`
+			} `json:"added"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(f.Received("workspace/didChangeWorkspaceFolders")[0], &change); err != nil {
+		t.Fatal(err)
+	}
+	if len(change.Event.Added) != 1 || change.Event.Added[0].URI != pathToURI(extra) {
+		t.Errorf("added = %+v, want %s", change.Event.Added, extra)
+	}
+}
@@ -21,40 +21,85 @@ import (
 
 // Service implements gopls-backed MCP tools.
 type Service struct {
-	root        string
-	rootURI     string
-	client      *gopls.Client
-	docs        *gopls.DocumentManager
-	diagnostics *diagHub
-
-	initOnce sync.Once
-	initErr  error
+	opts     Options
+	recorder *gopls.Recorder
+
+	mu       sync.Mutex
+	current  *session            // The workspace tools run against by default
+	sessions map[string]*session // By root, including current (see pool.go)
+	reaping  bool
+	closed   bool
+	done     chan struct{}
+	// rootsSynced is closed once the workspace reported by the MCP client's
+	// roots is in place (immediately when roots are not used).
+	rootsSynced chan struct{}
 }
 
-func NewService(ctx context.Context) (*Service, error) {
-	root, err := workspace.DetectRoot(".")
-	if err != nil {
+func NewService(ctx context.Context, opts Options) (*Service, error) {
+	if err := opts.validateTools(); err != nil {
 		return nil, err
 	}
-	if err := workspace.ValidateRoot(root); err != nil {
+	opts.applyDefaults()
+
+	root := opts.Root
+	if root == "" {
+		detected, err := workspace.DetectRoot(".")
+		if err != nil {
+			return nil, err
+		}
+		root = detected
+	}
+	var recorder *gopls.Recorder
+	if opts.RecordPath != "" {
+		var err error
+		if recorder, err = gopls.NewRecorder(opts.RecordPath, root); err != nil {
+			return nil, err
+		}
+	}
+	current, err := newSession(root, opts.ExtraRoots, opts, recorder)
+	if err != nil {
 		return nil, err
 	}
-	rootURI := pathToURI(root)
 
+	synced := make(chan struct{})
+	close(synced)
 	return &Service{
-		root:        root,
-		rootURI:     rootURI,
-		diagnostics: newDiagHub(),
+		opts:        opts,
+		recorder:    recorder,
+		current:     current,
+		sessions:    map[string]*session{current.root: current},
+		done:        make(chan struct{}),
+		rootsSynced: synced,
 	}, nil
 }
 
 func (s *Service) Close() error {
-	if s.client != nil {
-		return s.client.Close()
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
 	}
-	return nil
+	s.closed = true
+	close(s.done)
+	sessions := s.sessions
+	s.sessions = nil
+	s.mu.Unlock()
+
+	var err error
+	for _, ws := range sessions {
+		if closeErr := ws.close(); err == nil {
+			err = closeErr
+		}
+	}
+	if recErr := s.recorder.Close(); err == nil {
+		err = recErr
+	}
+	return err
 }
 
+// Register adds the tools and resources to server. The SDK cancels a tool's
+// context when the client sends notifications/cancelled; gopls.Client then
+// forwards the cancellation to gopls as $/cancelRequest.
 func (s *Service) Register(server *sdk.Server) {
 	// Primary tool: search for symbols by name
 	sdk.AddTool(server, &sdk.Tool{
@@ -70,7 +115,7 @@ Examples:
 
 Usage: Just provide a query string. Results include symbol name, kind, file path, and line number.
 Then use explain_symbol to understand any symbol you find.`,
-	}, s.SearchSymbols)
+	}, bind(s, (*session).SearchSymbols))
 
 	// Primary tool: understand a symbol completely
 	sdk.AddTool(server, &sdk.Tool{
@@ -86,8 +131,27 @@ USE THIS to understand any function, type, method, or variable. Returns everythi
 
 This replaces the need for separate definition/hover/references calls.
 
-Usage: file_path + symbol name. File content is read from disk automatically.`,
-	}, s.ExplainSymbol)
+Symbols can be qualified to pick the right declaration: "Service.Close", "(*Client).SendRequest",
+"gopls.Client.Close" or "import/path.Name". Ambiguous names return an error listing the candidates.
+
+Usage: file_path + symbol name. File content is read from disk automatically.
+file_path may be omitted when the symbol is qualified.
+Alternatively pass file_path + line/col (1-based) or a byte offset to query the exact
+identifier there, including locals and struct fields.`,
+	}, bind(s, (*session).ExplainSymbol))
+
+	// Tool for skimming a file without reading all of it
+	sdk.AddTool(server, &sdk.Tool{
+		Name: "list_symbols",
+		Description: `List the outline of a Go file: types with their fields and methods, functions, constants and variables.
+
+USE THIS to skim a large (e.g. generated) file before reading it. Each entry has its line range
+and declaration line; set include_body to also get the source.
+
+Filter with include_kinds / exclude_kinds (e.g. ["struct", "method"]) and max_results (default 100).
+
+Usage: file_path.`,
+	}, bind(s, (*session).ListSymbols))
 
 	// Primary tool: understand call flow
 	sdk.AddTool(server, &sdk.Tool{
@@ -99,10 +163,16 @@ USE THIS to understand code flow:
 - "outgoing": what does this function call? (callees)
 - "both": show both directions (default)
 
+Set depth > 1 to follow calls several levels deep (e.g. RPC handler → service → DAO).
+The result then also contains a deduplicated call graph as nodes + edges.
+Use same_package / exclude_tests to stop at package boundaries or skip tests.
+Set format to "mermaid" or "dot" to get a diagram clustered by package.
+
 Essential for: tracing request flow, understanding dependencies, assessing refactoring impact.
 
-Usage: file_path + symbol name. Direction defaults to "both".`,
-	}, s.GetCallHierarchy)
+Usage: file_path + symbol name (qualified names like "Service.Close" are supported),
+or file_path + line/col / offset. Direction defaults to "both".`,
+	}, bind(s, (*session).GetCallHierarchy))
 
 	// Tool for exploring external dependencies (go/pkg/mod)
 	sdk.AddTool(server, &sdk.Tool{
@@ -122,49 +192,116 @@ Examples:
 - import_path: "github.com/xxx/idl/user", symbol: "GetUserInfoRequest"
 
 Returns: Type definition, fields (for structs), methods, documentation.`,
-	}, s.ExplainImport)
+	}, bind(s, (*session).ExplainImport))
+
+	// Tool for navigating interface satisfaction
+	sdk.AddTool(server, &sdk.Tool{
+		Name: "find_implementations",
+		Description: `Find which types implement an interface, or which interfaces a type satisfies.
+
+USE THIS to navigate interface-based code:
+- On an interface (or interface method): returns the concrete types (or methods) implementing it
+- On a struct/type (or concrete method): returns the interfaces (or interface methods) it satisfies
+
+Results include location and the declaration line. Workspace only unless include_external is true.
+
+Usage: file_path + symbol name (qualified names supported), or file_path + line/col.`,
+	}, bind(s, (*session).FindImplementations))
+
+	// Tool for understanding how interface families are layered
+	sdk.AddTool(server, &sdk.Tool{
+		Name: "get_type_hierarchy",
+		Description: `Show the type hierarchy of a Go type or interface.
+
+USE THIS to understand how interfaces and types are layered:
+- "supertypes": interfaces the type satisfies and types it embeds
+- "subtypes": types implementing (or embedding) it
+- "both": show both directions (default)
+
+Each entry has a relation ("embeds" or "implements") and a parent ID, so depth > 1
+forms a tree rooted at "t0". Requires gopls v0.16+.
+
+Usage: file_path + type name (qualified names supported), or file_path + line/col.`,
+	}, bind(s, (*session).GetTypeHierarchy))
+
+	// Tool for summarizing a whole package API
+	sdk.AddTool(server, &sdk.Tool{
+		Name: "describe_package",
+		Description: `Summarize the exported API of a Go package, grouped like "go doc -all".
+
+USE THIS to get an overview of a package before reading individual symbols:
+- Package documentation and file counts
+- Exported constants, variables and functions
+- Exported types with their constructors and methods
+
+Set compact to true to only list signatures (no docs, type bodies collapsed) and save tokens.
+
+Examples:
+- package: "encoding/json"
+- package: "internal/mcp" (directory, workspace-relative)`,
+	}, bind(s, (*session).DescribePackage))
+
+	// Tool for safe, compiler-checked renames
+	sdk.AddTool(server, &sdk.Tool{
+		Name: "rename_symbol",
+		Description: `Rename a Go identifier across the workspace using gopls.
+
+USE THIS instead of search-and-replace when renaming functions, types, methods, fields or variables:
+- Type-checked: only real references are renamed, including other packages
+- Returns a unified diff per file so the change can be reviewed first
+- Dry run by default; set apply to true to write the files to disk
+
+Usage: file_path + symbol name (qualified names supported), or file_path + line/col; new_name is required.`,
+	}, bind(s, (*session).RenameSymbol))
+
+	// Tool for checking whether code still builds
+	sdk.AddTool(server, &sdk.Tool{
+		Name: "get_diagnostics",
+		Description: `Get compile, type and vet diagnostics reported by gopls.
+
+USE THIS after editing code to check whether it still builds:
+- file_path: diagnostics for a single file
+- package: diagnostics for a package directory or import path
+- neither: diagnostics for the whole workspace
+- code: check unsaved content instead, either as the content of file_path or as an extra file of package; nothing is written to disk
+
+Only errors are returned by default; set min_severity to "warning", "info" or "hint" for more.
+
+Usage: optional file_path or package. Waits up to wait_ms (default 3000) for gopls to finish analysis.`,
+	}, bind(s, (*session).GetDiagnostics))
+
+	// Tool for validating a change before it is written
+	sdk.AddTool(server, &sdk.Tool{
+		Name: "analyze_code",
+		Description: `Check unsaved Go code with gopls without writing it to disk.
+
+USE THIS to validate a patch before saving it: pass the complete new content of a file and where it will live.
+- The code is type-checked as that file, in the context of its package and module
+- Returns errors with the quick fixes gopls suggests (e.g. missing imports)
+- Set include_warnings to also get warnings, info and hints
+
+Usage: code + file_path (the file need not exist yet, but its directory must).`,
+	}, bind(s, (*session).AnalyzeCode))
 
 	server.AddResource(&sdk.Resource{
 		URI:         "byte-lsp://about",
 		Name:        "byte-lsp-mcp",
 		Title:       "Byte LSP MCP Server",
-		Description: "Go language analysis tools: search_symbols, explain_symbol, explain_import, get_call_hierarchy.",
+		Description: "Go language analysis tools: search_symbols, explain_symbol, explain_import, get_call_hierarchy, get_diagnostics, find_implementations, get_type_hierarchy, list_symbols, describe_package, rename_symbol.",
 		MIMEType:    "text/plain",
 	}, s.readAbout)
-}
-
-func (s *Service) Initialize(ctx context.Context) error {
-	s.initOnce.Do(func() {
-		client, err := gopls.NewClient(&gopls.Config{Workdir: s.root})
-		if err != nil {
-			s.initErr = err
-			return
-		}
-		s.client = client
-		s.docs = gopls.NewDocumentManager(client)
-
-		client.OnNotification("textDocument/publishDiagnostics", func(raw json.RawMessage) {
-			diags := parsePublishDiagnostics(raw)
-			if diags == nil {
-				return
-			}
-			s.diagnostics.Update(diags.URI, diags.Diagnostics)
-		})
 
-		ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
-		defer cancel()
-		s.initErr = client.Initialize(ctx, s.rootURI, []string{s.rootURI})
-	})
-	return s.initErr
+	s.removeDisabledTools(server)
 }
 
-func (s *Service) SearchSymbols(ctx context.Context, _ *sdk.CallToolRequest, input tools.SearchSymbolsInput) (*sdk.CallToolResult, tools.SearchSymbolsOutput, error) {
+func (s *session) SearchSymbols(ctx context.Context, _ *sdk.CallToolRequest, input tools.SearchSymbolsInput) (*sdk.CallToolResult, tools.SearchSymbolsOutput, error) {
 	if input.Query == "" {
 		return nil, tools.SearchSymbolsOutput{}, errors.New("query is required")
 	}
 	if err := s.Initialize(ctx); err != nil {
 		return nil, tools.SearchSymbolsOutput{}, err
 	}
+	notice := s.awaitWorkspaceLoad(ctx, input.NoIndexWait)
 
 	params := map[string]interface{}{
 		"query": input.Query,
@@ -178,18 +315,16 @@ func (s *Service) SearchSymbols(ctx context.Context, _ *sdk.CallToolRequest, inp
 		return nil, tools.SearchSymbolsOutput{}, err
 	}
 	if !input.IncludeExternal {
-		items = filterSymbolsInWorkspace(items, s.root)
+		items = filterSymbolsInWorkspace(items, s.workspaceRoots())
 	}
-	return nil, tools.SearchSymbolsOutput{Symbols: items}, nil
+	return nil, tools.SearchSymbolsOutput{Symbols: items, Notice: notice}, nil
 }
 
-func (s *Service) ExplainSymbol(ctx context.Context, _ *sdk.CallToolRequest, input tools.ExplainSymbolInput) (*sdk.CallToolResult, tools.ExplainSymbolOutput, error) {
-	if input.FilePath == "" || input.Symbol == "" {
-		return nil, tools.ExplainSymbolOutput{}, errors.New("file_path and symbol are required")
-	}
+func (s *session) ExplainSymbol(ctx context.Context, _ *sdk.CallToolRequest, input tools.ExplainSymbolInput) (*sdk.CallToolResult, tools.ExplainSymbolOutput, error) {
 	if err := s.Initialize(ctx); err != nil {
 		return nil, tools.ExplainSymbolOutput{}, err
 	}
+	notice := s.awaitWorkspaceLoad(ctx, input.NoIndexWait)
 
 	// Set defaults
 	includeSource := true
@@ -203,27 +338,18 @@ func (s *Service) ExplainSymbol(ctx context.Context, _ *sdk.CallToolRequest, inp
 	}
 	maxRefs := input.MaxReferences
 	if maxRefs <= 0 {
-		maxRefs = 10
+		maxRefs = s.opts.MaxReferences
 	}
 
-	// Read file from disk
-	absPath, err := s.resolveDiskPath(input.FilePath)
-	if err != nil {
-		return nil, tools.ExplainSymbolOutput{}, err
-	}
-	code, err := os.ReadFile(absPath)
+	// Find symbol position (file is read from disk)
+	target, err := s.resolveTarget(ctx, input.FilePath, input.Symbol, input.Line, input.Col, input.Offset)
 	if err != nil {
 		return nil, tools.ExplainSymbolOutput{}, err
 	}
-
-	// Find symbol position
-	line, col, ok := tools.FindSymbolPosition(string(code), input.Symbol)
-	if !ok {
-		return nil, tools.ExplainSymbolOutput{}, errors.New("symbol not found in file")
-	}
+	line, col := target.line, target.col
 
 	// Prepare document
-	_, uri, err := s.prepareDocument(ctx, absPath, string(code))
+	_, uri, err := s.prepareDocument(ctx, target.absPath)
 	if err != nil {
 		return nil, tools.ExplainSymbolOutput{}, err
 	}
@@ -232,6 +358,9 @@ func (s *Service) ExplainSymbol(ctx context.Context, _ *sdk.CallToolRequest, inp
 	output := tools.ExplainSymbolOutput{
 		Name: input.Symbol,
 	}
+	if output.Name == "" {
+		output.Name = target.name
+	}
 
 	// 1. Get hover info (signature + documentation)
 	hoverParams := map[string]any{
@@ -252,11 +381,17 @@ func (s *Service) ExplainSymbol(ctx context.Context, _ *sdk.CallToolRequest, inp
 	if defRaw, err := s.client.SendRequest(ctx, "textDocument/definition", defParams); err == nil {
 		if locs, err := tools.ParseLocations(defRaw); err == nil && len(locs) > 0 {
 			output.DefinedAt = &locs[0]
-			output.Kind = inferSymbolKind(string(code), input.Symbol)
+			output.Kind = target.kind
+			if output.Kind == "" {
+				output.Kind = kindAtDefinition(locs[0])
+			}
+			if output.Kind == "" {
+				output.Kind = inferSymbolKind(target.code, target.name)
+			}
 
 			// 3. Extract source code if requested
 			if includeSource {
-				output.Source = extractSymbolSource(locs[0].FilePath, locs[0].Line)
+				output.Source = s.extractSymbolSource(locs[0].FilePath, locs[0].Line)
 			}
 		}
 	}
@@ -288,16 +423,15 @@ func (s *Service) ExplainSymbol(ctx context.Context, _ *sdk.CallToolRequest, inp
 		}
 	}
 
+	output.Notice = notice
 	return nil, output, nil
 }
 
-func (s *Service) GetCallHierarchy(ctx context.Context, _ *sdk.CallToolRequest, input tools.GetCallHierarchyInput) (*sdk.CallToolResult, tools.GetCallHierarchyOutput, error) {
-	if input.FilePath == "" || input.Symbol == "" {
-		return nil, tools.GetCallHierarchyOutput{}, errors.New("file_path and symbol are required")
-	}
+func (s *session) GetCallHierarchy(ctx context.Context, _ *sdk.CallToolRequest, input tools.GetCallHierarchyInput) (*sdk.CallToolResult, tools.GetCallHierarchyOutput, error) {
 	if err := s.Initialize(ctx); err != nil {
 		return nil, tools.GetCallHierarchyOutput{}, err
 	}
+	notice := s.awaitWorkspaceLoad(ctx, input.NoIndexWait)
 
 	// Set defaults
 	direction := input.Direction
@@ -307,25 +441,38 @@ func (s *Service) GetCallHierarchy(ctx context.Context, _ *sdk.CallToolRequest,
 	if direction != "incoming" && direction != "outgoing" && direction != "both" {
 		return nil, tools.GetCallHierarchyOutput{}, errors.New("direction must be 'incoming', 'outgoing', or 'both'")
 	}
-
-	// Read file from disk
-	absPath, err := s.resolveDiskPath(input.FilePath)
-	if err != nil {
-		return nil, tools.GetCallHierarchyOutput{}, err
+	depth := input.Depth
+	if depth <= 0 {
+		depth = 1
 	}
-	code, err := os.ReadFile(absPath)
-	if err != nil {
-		return nil, tools.GetCallHierarchyOutput{}, err
+	if depth > maxCallDepth {
+		depth = maxCallDepth
+	}
+	maxFanout := input.MaxFanout
+	if maxFanout <= 0 {
+		maxFanout = defaultMaxFanout
+	}
+	maxNodes := input.MaxNodes
+	if maxNodes <= 0 {
+		maxNodes = defaultMaxNodes
+	}
+	format := input.Format
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "mermaid" && format != "dot" {
+		return nil, tools.GetCallHierarchyOutput{}, errors.New("format must be 'json', 'mermaid', or 'dot'")
 	}
 
-	// Find symbol position
-	line, col, ok := tools.FindSymbolPosition(string(code), input.Symbol)
-	if !ok {
-		return nil, tools.GetCallHierarchyOutput{}, errors.New("symbol not found in file")
+	// Find symbol position (file is read from disk)
+	target, err := s.resolveTarget(ctx, input.FilePath, input.Symbol, input.Line, input.Col, input.Offset)
+	if err != nil {
+		return nil, tools.GetCallHierarchyOutput{}, err
 	}
+	line, col := target.line, target.col
 
 	// Prepare document
-	_, uri, err := s.prepareDocument(ctx, absPath, string(code))
+	_, uri, err := s.prepareDocument(ctx, target.absPath)
 	if err != nil {
 		return nil, tools.GetCallHierarchyOutput{}, err
 	}
@@ -364,6 +511,9 @@ func (s *Service) GetCallHierarchy(ctx context.Context, _ *sdk.CallToolRequest,
 		}
 		if incomingRaw, err := s.client.SendRequest(ctx, "callHierarchy/incomingCalls", incomingParams); err == nil {
 			if incoming, err := tools.ParseCallHierarchyIncoming(incomingRaw); err == nil {
+				if input.ExcludeTests {
+					incoming = filterTestCalls(incoming)
+				}
 				// Add context for each caller
 				for i := range incoming {
 					incoming[i].Context = getLineContent(incoming[i].FilePath, incoming[i].Line)
@@ -380,6 +530,9 @@ func (s *Service) GetCallHierarchy(ctx context.Context, _ *sdk.CallToolRequest,
 		}
 		if outgoingRaw, err := s.client.SendRequest(ctx, "callHierarchy/outgoingCalls", outgoingParams); err == nil {
 			if outgoing, err := tools.ParseCallHierarchyOutgoing(outgoingRaw); err == nil {
+				if input.ExcludeTests {
+					outgoing = filterTestCalls(outgoing)
+				}
 				// Add context for each callee
 				for i := range outgoing {
 					outgoing[i].Context = getLineContent(outgoing[i].FilePath, outgoing[i].Line)
@@ -389,10 +542,51 @@ func (s *Service) GetCallHierarchy(ctx context.Context, _ *sdk.CallToolRequest,
 		}
 	}
 
+	// Step 4: Walk further levels into a call graph (diagrams always need one)
+	if depth > 1 || format != "json" {
+		builder := newCallGraphBuilder(s, item, callGraphOptions{
+			depth:        depth,
+			maxFanout:    maxFanout,
+			maxNodes:     maxNodes,
+			samePackage:  input.SamePackage,
+			excludeTests: input.ExcludeTests,
+		})
+		if direction == "incoming" || direction == "both" {
+			builder.Walk(ctx, item, "incoming")
+		}
+		if direction == "outgoing" || direction == "both" {
+			builder.Walk(ctx, item, "outgoing")
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, tools.GetCallHierarchyOutput{}, err
+		}
+		output.Nodes, output.Edges, output.Truncated = builder.Result()
+	}
+
+	switch format {
+	case "mermaid":
+		output.Diagram = tools.RenderCallGraphMermaid(output.Nodes, output.Edges)
+	case "dot":
+		output.Diagram = tools.RenderCallGraphDOT(output.Nodes, output.Edges)
+	}
+
+	output.Notice = notice
 	return nil, output, nil
 }
 
-func (s *Service) ExplainImport(ctx context.Context, _ *sdk.CallToolRequest, input tools.ExplainImportInput) (*sdk.CallToolResult, tools.ExplainImportOutput, error) {
+// filterTestCalls drops call hierarchy items defined in _test.go files.
+func filterTestCalls(items []tools.CallHierarchyItem) []tools.CallHierarchyItem {
+	filtered := make([]tools.CallHierarchyItem, 0, len(items))
+	for _, item := range items {
+		if strings.HasSuffix(item.FilePath, "_test.go") {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+	return filtered
+}
+
+func (s *session) ExplainImport(ctx context.Context, _ *sdk.CallToolRequest, input tools.ExplainImportInput) (*sdk.CallToolResult, tools.ExplainImportOutput, error) {
 	if input.ImportPath == "" || input.Symbol == "" {
 		return nil, tools.ExplainImportOutput{}, errors.New("import_path and symbol are required")
 	}
@@ -475,7 +669,7 @@ func inferSymbolKind(code, symbol string) string {
 }
 
 // extractSymbolSource extracts the source code of a symbol definition.
-func extractSymbolSource(filePath string, startLine int) string {
+func (s *session) extractSymbolSource(filePath string, startLine int) string {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return ""
@@ -490,7 +684,7 @@ func extractSymbolSource(filePath string, startLine int) string {
 	braceCount := 0
 	started := false
 
-	for i := startLine - 1; i < len(lines) && i < startLine+100; i++ {
+	for i := startLine - 1; i < len(lines) && i < startLine+s.opts.SourceMaxLines; i++ {
 		line := lines[i]
 		result = append(result, line)
 
@@ -512,8 +706,8 @@ func extractSymbolSource(filePath string, startLine int) string {
 
 	// Limit output size
 	source := strings.Join(result, "\n")
-	if len(source) > 2000 {
-		source = source[:2000] + "\n// ... (truncated)"
+	if len(source) > s.opts.SourceMaxChars {
+		source = source[:s.opts.SourceMaxChars] + "\n// ... (truncated)"
 	}
 	return source
 }
@@ -532,7 +726,14 @@ func getLineContent(filePath string, lineNum int) string {
 }
 
 func (s *Service) readAbout(ctx context.Context, _ *sdk.ReadResourceRequest) (*sdk.ReadResourceResult, error) {
+	ws := s.session()
 	content := "byte-lsp-mcp provides gopls-backed Go analysis tools: diagnostics, definition, references, hover, and symbol search."
+	content += "\nworkspace folders: " + strings.Join(ws.workspaceRoots(), ", ")
+	content += fmt.Sprintf("\ngopls sessions (max %d): %s", s.opts.MaxSessions, strings.Join(s.sessionRoots(), ", "))
+	if client := ws.started(); client != nil {
+		content += fmt.Sprintf("\ngopls restarts: %d", client.Restarts())
+		content += "\ngopls status: " + loadingStatus(client)
+	}
 	return &sdk.ReadResourceResult{Contents: []*sdk.ResourceContents{
 		{
 			URI:      "byte-lsp://about",
@@ -542,59 +743,20 @@ func (s *Service) readAbout(ctx context.Context, _ *sdk.ReadResourceRequest) (*s
 	}}, nil
 }
 
-func (s *Service) prepareDocument(ctx context.Context, filePath, code string) (string, string, error) {
-	absPath, isVirtual, err := s.resolvePath(filePath)
+// prepareDocument opens filePath in gopls with its content on disk.
+func (s *session) prepareDocument(ctx context.Context, filePath string) (string, string, error) {
+	absPath, err := filepath.Abs(filePath)
 	if err != nil {
 		return "", "", err
 	}
-	if isVirtual {
-		if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
-			return "", "", err
-		}
-		if err := os.WriteFile(absPath, []byte(code), 0o644); err != nil {
-			return "", "", err
-		}
-	}
 	uri := pathToURI(absPath)
-	if _, err := s.docs.OpenOrUpdate(ctx, uri, "go", code); err != nil {
+	if _, _, err := s.docs.OpenFile(ctx, uri, absPath); err != nil {
 		return "", "", err
 	}
 	return absPath, uri, nil
 }
 
-func (s *Service) resolvePath(filePath string) (string, bool, error) {
-	cleaned := filepath.Clean(filePath)
-	if cleaned == "" || cleaned == "." {
-		return "", false, errors.New("file_path cannot be empty")
-	}
-
-	virtualBase := filepath.Join(s.root, "mcp_virtual")
-	sep := string(os.PathSeparator)
-
-	if filepath.IsAbs(cleaned) {
-		if strings.HasPrefix(cleaned, s.root+sep) {
-			if _, err := os.Stat(cleaned); err == nil {
-				return cleaned, false, nil
-			}
-			return cleaned, true, nil
-		}
-		base := filepath.Base(cleaned)
-		return filepath.Join(virtualBase, base), true, nil
-	}
-
-	if cleaned == ".." || strings.HasPrefix(cleaned, ".."+sep) {
-		base := filepath.Base(cleaned)
-		return filepath.Join(virtualBase, base), true, nil
-	}
-
-	candidate := filepath.Join(s.root, cleaned)
-	if _, err := os.Stat(candidate); err == nil {
-		return candidate, false, nil
-	}
-	return filepath.Join(virtualBase, cleaned), true, nil
-}
-
-func (s *Service) resolveDiskPath(filePath string) (string, error) {
+func (s *session) resolveDiskPath(filePath string) (string, error) {
 	cleaned := filepath.Clean(filePath)
 	if cleaned == "" || cleaned == "." {
 		return "", errors.New("file_path cannot be empty")
@@ -620,8 +782,8 @@ func (s *Service) resolveDiskPath(filePath string) (string, error) {
 	}
 }
 
-func (s *Service) warmupDocument(ctx context.Context, uri string) {
-	pullCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+func (s *session) warmupDocument(ctx context.Context, uri string) {
+	pullCtx, cancel := context.WithTimeout(ctx, s.opts.WarmupTimeout)
 	defer cancel()
 	params := map[string]interface{}{
 		"textDocument": map[string]interface{}{
@@ -634,6 +796,7 @@ func (s *Service) warmupDocument(ctx context.Context, uri string) {
 func parsePublishDiagnostics(raw json.RawMessage) *publishDiagnostics {
 	var payload struct {
 		URI         string          `json:"uri"`
+		Version     int             `json:"version"`
 		Diagnostics json.RawMessage `json:"diagnostics"`
 	}
 	if err := json.Unmarshal(raw, &payload); err != nil {
@@ -646,7 +809,7 @@ func parsePublishDiagnostics(raw json.RawMessage) *publishDiagnostics {
 	if err != nil {
 		return nil
 	}
-	return &publishDiagnostics{URI: payload.URI, Diagnostics: diags}
+	return &publishDiagnostics{URI: payload.URI, Version: payload.Version, Diagnostics: diags}
 }
 
 func pathToURI(path string) string {
@@ -656,70 +819,159 @@ func pathToURI(path string) string {
 
 type publishDiagnostics struct {
 	URI         string             `json:"uri"`
+	Version     int                `json:"version"` // Document version, 0 if not given
 	Diagnostics []tools.Diagnostic `json:"diagnostics"`
 }
 
 // diagHub stores latest diagnostics and allows waiting for updates.
 type diagHub struct {
-	mu      sync.Mutex
-	latest  map[string][]tools.Diagnostic
-	waiters map[string][]chan []tools.Diagnostic
+	mu         sync.Mutex
+	latest     map[string][]tools.Diagnostic
+	versions   map[string]int // Document version of latest, 0 if unknown
+	waiters    map[string][]diagWaiter
+	lastUpdate time.Time
+}
+
+// diagWaiter waits for diagnostics of at least version (any if 0).
+type diagWaiter struct {
+	version int
+	ch      chan []tools.Diagnostic
 }
 
 func newDiagHub() *diagHub {
 	return &diagHub{
-		latest:  make(map[string][]tools.Diagnostic),
-		waiters: make(map[string][]chan []tools.Diagnostic),
+		latest:   make(map[string][]tools.Diagnostic),
+		versions: make(map[string]int),
+		waiters:  make(map[string][]diagWaiter),
 	}
 }
 
-func (h *diagHub) Update(uri string, diags []tools.Diagnostic) {
+// Update stores diagnostics published for version of uri (0 if unknown).
+func (h *diagHub) Update(uri string, version int, diags []tools.Diagnostic) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	h.latest[uri] = diags
-	for _, ch := range h.waiters[uri] {
-		ch <- diags
-		close(ch)
+	h.versions[uri] = version
+	h.lastUpdate = time.Now()
+	var pending []diagWaiter
+	for _, w := range h.waiters[uri] {
+		if version < w.version {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- diags
+		close(w.ch)
+	}
+	if len(pending) > 0 {
+		h.waiters[uri] = pending
+	} else {
+		delete(h.waiters, uri)
 	}
-	delete(h.waiters, uri)
 }
 
 func (h *diagHub) Wait(uri string, timeout time.Duration) []tools.Diagnostic {
+	diags, _ := h.WaitVersion(uri, 0, timeout)
+	return diags
+}
+
+// WaitVersion waits for diagnostics published for version of uri or a later
+// one, so that results for older content are never returned. It reports
+// false if none arrived within timeout.
+func (h *diagHub) WaitVersion(uri string, version int, timeout time.Duration) ([]tools.Diagnostic, bool) {
 	h.mu.Lock()
-	if diags, ok := h.latest[uri]; ok {
+	if diags, ok := h.latest[uri]; ok && h.versions[uri] >= version {
 		h.mu.Unlock()
-		return diags
+		return diags, true
 	}
 	ch := make(chan []tools.Diagnostic, 1)
-	h.waiters[uri] = append(h.waiters[uri], ch)
+	h.waiters[uri] = append(h.waiters[uri], diagWaiter{version: version, ch: ch})
 	h.mu.Unlock()
 
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
 	select {
 	case diags := <-ch:
-		return diags
-	case <-time.After(timeout):
-		return nil
+		return diags, true
+	case <-timer.C:
+		return nil, false
 	}
 }
 
-func filterSymbolsInWorkspace(items []tools.SymbolInformation, root string) []tools.SymbolInformation {
-	rootAbs, err := filepath.Abs(root)
-	if err != nil {
-		return items
+// Forget drops the cached diagnostics of uri.
+func (h *diagHub) Forget(uri string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.latest, uri)
+	delete(h.versions, uri)
+}
+
+// Latest returns the cached diagnostics for uri without waiting.
+func (h *diagHub) Latest(uri string) ([]tools.Diagnostic, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	diags, ok := h.latest[uri]
+	return diags, ok
+}
+
+// Snapshot returns a copy of all cached diagnostics keyed by URI.
+func (h *diagHub) Snapshot() map[string][]tools.Diagnostic {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make(map[string][]tools.Diagnostic, len(h.latest))
+	for uri, diags := range h.latest {
+		out[uri] = diags
 	}
+	return out
+}
+
+// WaitQuiet blocks until no diagnostics have been published for quiet,
+// or until timeout elapses.
+func (h *diagHub) WaitQuiet(ctx context.Context, quiet, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		h.mu.Lock()
+		last := h.lastUpdate
+		h.mu.Unlock()
+		if !last.IsZero() && time.Since(last) >= quiet {
+			return
+		}
+		if time.Now().After(deadline) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func filterSymbolsInWorkspace(items []tools.SymbolInformation, roots []string) []tools.SymbolInformation {
 	filtered := make([]tools.SymbolInformation, 0, len(items))
 	for _, item := range items {
 		if item.FilePath == "" {
 			continue
 		}
-		if inWorkspace(rootAbs, item.FilePath) {
+		if inWorkspace(roots, item.FilePath) {
 			filtered = append(filtered, item)
 		}
 	}
 	return filtered
 }
 
-func inWorkspace(rootAbs, filePath string) bool {
+// inWorkspace reports whether filePath is under any of the absolute roots.
+func inWorkspace(roots []string, filePath string) bool {
+	for _, root := range roots {
+		if inRoot(root, filePath) {
+			return true
+		}
+	}
+	return false
+}
+
+func inRoot(rootAbs, filePath string) bool {
 	fileAbs, err := filepath.Abs(filePath)
 	if err != nil {
 		return false
@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dreamcats/bytelsp/internal/gopls/goplstest"
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+// fakeRoot returns the workspace the fake gopls tests run in.
+func fakeRoot(t *testing.T) string {
+	t.Helper()
+	root, err := filepath.Abs("testdata/replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestSearchSymbolsFiltersExternal(t *testing.T) {
+	root := fakeRoot(t)
+	f := goplstest.NewServer()
+	f.Respond("workspace/symbol", []map[string]any{
+		{"name": "Greeter", "kind": 23, "location": map[string]any{
+			"uri":   pathToURI(filepath.Join(root, "main.go")),
+			"range": map[string]any{"start": map[string]any{"line": 5, "character": 5}, "end": map[string]any{"line": 5, "character": 12}},
+		}},
+		{"name": "Greeter", "kind": 11, "location": map[string]any{
+			"uri":   "file:///usr/lib/go/src/other/other.go",
+			"range": map[string]any{"start": map[string]any{"line": 0, "character": 5}, "end": map[string]any{"line": 0, "character": 12}},
+		}},
+	})
+	cs, _ := connect(t, Options{Root: root, GoplsDial: f.Dial})
+
+	var out tools.SearchSymbolsOutput
+	callTool(t, cs, "search_symbols", map[string]any{"query": "Greeter", "no_index_wait": true}, &out)
+	want := tools.SymbolInformation{Name: "Greeter", Kind: "Struct", FilePath: filepath.Join(root, "main.go"), Line: 6, Col: 6}
+	if len(out.Symbols) != 1 || out.Symbols[0] != want {
+		t.Errorf("symbols = %+v, want [%+v]", out.Symbols, want)
+	}
+
+	var params struct {
+		Query string `json:"query"`
+	}
+	if received := f.Received("workspace/symbol"); len(received) != 1 {
+		t.Fatalf("workspace/symbol sent %d times, want 1", len(received))
+	} else if err := json.Unmarshal(received[0], &params); err != nil || params.Query != "Greeter" {
+		t.Errorf("workspace/symbol params = %s", received[0])
+	}
+
+	callTool(t, cs, "search_symbols", map[string]any{"query": "Greeter", "include_external": true, "no_index_wait": true}, &out)
+	if len(out.Symbols) != 2 {
+		t.Errorf("with include_external: %d symbols, want 2", len(out.Symbols))
+	}
+}
+
+// waitFor polls cond until it holds or a few seconds have passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPositionsAreUTF16(t *testing.T) {
+	root := t.TempDir()
+	// "é" is two bytes and one UTF-16 unit, "😀" four bytes and two units.
+	code := "package main\n\nvar s = \"é😀\"; var greeting = 1\n"
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f := goplstest.NewServer()
+	f.Respond("textDocument/hover", nil)
+	cs, _ := connect(t, Options{Root: root, GoplsDial: f.Dial})
+
+	for _, args := range []map[string]any{
+		{"file_path": "main.go", "symbol": "greeting", "no_index_wait": true},
+		{"file_path": "main.go", "line": 3, "col": 20, "no_index_wait": true},
+		{"file_path": "main.go", "offset": 36, "no_index_wait": true},
+	} {
+		var out tools.ExplainSymbolOutput
+		callTool(t, cs, "explain_symbol", args, &out)
+		if out.Name != "greeting" {
+			t.Errorf("%v: name = %q, want greeting", args, out.Name)
+		}
+		received := f.Received("textDocument/hover")
+		var params struct {
+			Position struct {
+				Line      int `json:"line"`
+				Character int `json:"character"`
+			} `json:"position"`
+		}
+		if err := json.Unmarshal(received[len(received)-1], &params); err != nil {
+			t.Fatal(err)
+		}
+		if params.Position.Line != 2 || params.Position.Character != 19 {
+			t.Errorf("%v: hover at %+v, want line 2 character 19", args, params.Position)
+		}
+	}
+}
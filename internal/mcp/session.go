@@ -0,0 +1,202 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dreamcats/bytelsp/internal/gopls"
+	"github.com/dreamcats/bytelsp/internal/workspace"
+)
+
+// session is one workspace with its own gopls. Tool handlers run against
+// a session; the Service picks which one.
+type session struct {
+	root        string
+	rootURI     string
+	client      *gopls.Client
+	docs        *gopls.DocumentManager
+	diagnostics *diagHub
+	recorder    *gopls.Recorder
+	opts        Options
+
+	// foldersMu serializes the workspace folder changes sent to gopls.
+	foldersMu sync.Mutex
+
+	mu          sync.Mutex
+	roots       []string        // Absolute workspace folders: root, its go.work modules, extra roots
+	initialized bool            // gopls accepted initialize; folder changes can be sent
+	snippets    map[string]bool // Snippet paths in use by calls in progress
+
+	// Pool bookkeeping, guarded by Service.mu.
+	active   int // Tool calls in progress
+	lastUsed time.Time
+
+	initOnce sync.Once
+	initErr  error
+}
+
+// newSession prepares a session for root; gopls starts on first use.
+// extra are additional workspace folders.
+func newSession(root string, extra []string, opts Options, recorder *gopls.Recorder) (*session, error) {
+	if err := workspace.ValidateRoot(root); err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	roots, err := workspace.Folders(root, extra)
+	if err != nil {
+		return nil, err
+	}
+	return &session{
+		root:        root,
+		rootURI:     pathToURI(root),
+		roots:       roots,
+		diagnostics: newDiagHub(),
+		recorder:    recorder,
+		opts:        opts,
+	}, nil
+}
+
+func (s *session) Initialize(ctx context.Context) error {
+	s.initOnce.Do(func() {
+		client, err := gopls.NewClient(&gopls.Config{
+			GoplsPath:       s.opts.GoplsPath,
+			Args:            s.opts.GoplsArgs,
+			Workdir:         s.root,
+			Settings:        s.opts.GoplsSettings,
+			RequestTimeout:  s.opts.RequestTimeout,
+			Remote:          s.opts.GoplsRemote,
+			RemoteAutoStart: s.opts.GoplsAutoStart,
+			Dial:            s.opts.GoplsDial,
+			Recorder:        s.recorder,
+		})
+		if err != nil {
+			s.initErr = err
+			return
+		}
+		s.mu.Lock()
+		s.client = client
+		s.mu.Unlock()
+		s.docs = gopls.NewDocumentManager(client)
+
+		client.OnNotification("textDocument/publishDiagnostics", func(raw json.RawMessage) {
+			diags := parsePublishDiagnostics(raw)
+			if diags == nil {
+				return
+			}
+			s.diagnostics.Update(diags.URI, diags.Version, diags.Diagnostics)
+		})
+
+		// The first tool call may be cancelled by the MCP client; that must
+		// not leave the server permanently uninitialized.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.opts.InitTimeout)
+		defer cancel()
+		s.initErr = s.initializeClient(ctx, client)
+	})
+	if s.initErr != nil {
+		return s.initErr
+	}
+	// Other tools may have edited files since they were opened. A file that
+	// cannot be resynced must not fail calls that may not even use it.
+	if err := s.docs.Resync(ctx); err != nil {
+		log.Printf("resync documents: %v", err)
+	}
+	return nil
+}
+
+// initializeClient initializes client with the workspace folders. Until it
+// succeeds, setFolders only records changes, since gopls cannot take them
+// before initialize; the changes made meanwhile are sent afterwards.
+func (s *session) initializeClient(ctx context.Context, client *gopls.Client) error {
+	sent := s.workspaceRoots()
+	folders := make([]string, 0, len(sent))
+	for _, r := range sent {
+		folders = append(folders, pathToURI(r))
+	}
+	if err := client.Initialize(ctx, s.rootURI, folders); err != nil {
+		return err
+	}
+
+	s.foldersMu.Lock()
+	defer s.foldersMu.Unlock()
+	s.mu.Lock()
+	s.initialized = true
+	current := s.roots
+	s.mu.Unlock()
+	if added, removed := diffFolders(sent, current); len(added) > 0 || len(removed) > 0 {
+		if err := client.ChangeWorkspaceFolders(added, removed); err != nil {
+			log.Printf("workspace folders: %v", err)
+		}
+	}
+	return nil
+}
+
+// started returns the gopls client, or nil if gopls has not been started.
+func (s *session) started() *gopls.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client
+}
+
+func (s *session) close() error {
+	if client := s.started(); client != nil {
+		return client.Close()
+	}
+	return nil
+}
+
+// workspaceRoots returns the current workspace folders.
+func (s *session) workspaceRoots() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.roots
+}
+
+// setFolders replaces the workspace folders, telling a running gopls which
+// folders were added and removed.
+func (s *session) setFolders(folders []string) error {
+	s.foldersMu.Lock()
+	defer s.foldersMu.Unlock()
+	s.mu.Lock()
+	old := s.roots
+	s.roots = folders
+	client := s.client
+	initialized := s.initialized
+	s.mu.Unlock()
+	if client == nil || !initialized {
+		return nil // Sent by initializeClient
+	}
+
+	added, removed := diffFolders(old, folders)
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return client.ChangeWorkspaceFolders(added, removed)
+}
+
+// diffFolders returns the URIs of the folders only in next and only in prev.
+func diffFolders(prev, next []string) (added, removed []string) {
+	inPrev := make(map[string]bool, len(prev))
+	for _, f := range prev {
+		inPrev[f] = true
+	}
+	inNext := make(map[string]bool, len(next))
+	for _, f := range next {
+		inNext[f] = true
+		if !inPrev[f] {
+			added = append(added, pathToURI(f))
+		}
+	}
+	for _, f := range prev {
+		if !inNext[f] {
+			removed = append(removed, pathToURI(f))
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,22 @@
+package main
+
+import "fmt"
+
+// Greeter says hello.
+type Greeter struct {
+	Name string
+}
+
+// Greet returns the greeting for g.
+func (g *Greeter) Greet() string {
+	return hello(g.Name)
+}
+
+func hello(name string) string {
+	return fmt.Sprintf("hello, %s", name)
+}
+
+func main() {
+	g := &Greeter{Name: "world"}
+	fmt.Println(g.Greet())
+}
@@ -0,0 +1,266 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/dreamcats/bytelsp/internal/tools"
+)
+
+const maxTypeDepth = 5
+
+func (s *session) GetTypeHierarchy(ctx context.Context, _ *sdk.CallToolRequest, input tools.GetTypeHierarchyInput) (*sdk.CallToolResult, tools.GetTypeHierarchyOutput, error) {
+	if err := s.Initialize(ctx); err != nil {
+		return nil, tools.GetTypeHierarchyOutput{}, err
+	}
+	notice := s.awaitWorkspaceLoad(ctx, input.NoIndexWait)
+
+	direction := input.Direction
+	if direction == "" {
+		direction = "both"
+	}
+	if direction != "supertypes" && direction != "subtypes" && direction != "both" {
+		return nil, tools.GetTypeHierarchyOutput{}, errors.New("direction must be 'supertypes', 'subtypes', or 'both'")
+	}
+	depth := input.Depth
+	if depth <= 0 {
+		depth = 1
+	}
+	if depth > maxTypeDepth {
+		depth = maxTypeDepth
+	}
+	maxResults := input.MaxResults
+	if maxResults <= 0 {
+		maxResults = 100
+	}
+
+	target, err := s.resolveTarget(ctx, input.FilePath, input.Symbol, input.Line, input.Col, input.Offset)
+	if err != nil {
+		return nil, tools.GetTypeHierarchyOutput{}, err
+	}
+	_, uri, err := s.prepareDocument(ctx, target.absPath)
+	if err != nil {
+		return nil, tools.GetTypeHierarchyOutput{}, err
+	}
+	s.warmupDocument(ctx, uri)
+
+	items, err := s.prepareTypeHierarchy(ctx, uri, target.line, target.col)
+	if err != nil {
+		return nil, tools.GetTypeHierarchyOutput{}, fmt.Errorf("prepareTypeHierarchy (requires gopls v0.16+): %w", err)
+	}
+	if len(items) == 0 {
+		return nil, tools.GetTypeHierarchyOutput{}, errors.New("no type hierarchy item found for symbol")
+	}
+
+	root := items[0]
+	output := tools.GetTypeHierarchyOutput{
+		ID:       "t0",
+		Name:     root.Name,
+		Kind:     tools.SymbolKindToString(root.Kind),
+		FilePath: tools.URIToPath(root.URI),
+		Line:     root.Line(),
+	}
+
+	w := &typeWalker{s: s, depth: depth, maxResults: maxResults, ids: map[string]string{typeItemKey(root): "t0"}}
+	if direction == "supertypes" || direction == "both" {
+		output.Supertypes = w.walk(ctx, root, "supertypes")
+	}
+	if direction == "subtypes" || direction == "both" {
+		output.Subtypes = w.walk(ctx, root, "subtypes")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, tools.GetTypeHierarchyOutput{}, err
+	}
+	output.Truncated = w.truncated
+	output.Notice = notice
+	return nil, output, nil
+}
+
+func (s *session) prepareTypeHierarchy(ctx context.Context, uri string, line, col int) ([]tools.LSPTypeHierarchyItem, error) {
+	params := map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line - 1, "character": col - 1},
+	}
+	raw, err := s.client.SendRequest(ctx, "textDocument/prepareTypeHierarchy", params)
+	if err != nil {
+		return nil, err
+	}
+	return tools.ParseTypeHierarchyItems(raw)
+}
+
+// typeWalker expands typeHierarchy/supertypes and subtypes breadth-first and
+// adds embedding relationships found in the type declarations.
+type typeWalker struct {
+	s          *session
+	depth      int
+	maxResults int
+	ids        map[string]string
+	truncated  bool
+}
+
+type typeEntry struct {
+	item  tools.LSPTypeHierarchyItem
+	id    string
+	depth int
+}
+
+func (w *typeWalker) walk(ctx context.Context, root tools.LSPTypeHierarchyItem, direction string) []tools.TypeHierarchyItem {
+	var out []tools.TypeHierarchyItem
+	visited := map[string]bool{typeItemKey(root): true}
+	queue := []typeEntry{{item: root, id: "t0", depth: 0}}
+	for len(queue) > 0 && ctx.Err() == nil {
+		entry := queue[0]
+		queue = queue[1:]
+		if entry.depth >= w.depth {
+			continue
+		}
+
+		related := w.fetch(ctx, entry.item, direction)
+		if direction == "supertypes" {
+			related = w.addEmbedded(ctx, entry.item, related)
+		}
+		for _, rel := range related {
+			key := typeItemKey(rel.item)
+			if visited[key] {
+				continue
+			}
+			if len(out) >= w.maxResults {
+				w.truncated = true
+				return out
+			}
+			visited[key] = true
+			relation := rel.relation
+			if direction == "subtypes" && embedsType(rel.item, entry.item.Name) {
+				relation = "embeds"
+			}
+			id := w.id(rel.item)
+			out = append(out, tools.TypeHierarchyItem{
+				ID:       id,
+				Name:     rel.item.Name,
+				Kind:     tools.SymbolKindToString(rel.item.Kind),
+				FilePath: tools.URIToPath(rel.item.URI),
+				Line:     rel.item.Line(),
+				Col:      rel.item.Col(),
+				Detail:   rel.item.Detail,
+				Relation: relation,
+				Parent:   entry.id,
+				Depth:    entry.depth + 1,
+			})
+			queue = append(queue, typeEntry{item: rel.item, id: id, depth: entry.depth + 1})
+		}
+	}
+	return out
+}
+
+type relatedType struct {
+	item     tools.LSPTypeHierarchyItem
+	relation string
+}
+
+func (w *typeWalker) fetch(ctx context.Context, item tools.LSPTypeHierarchyItem, direction string) []relatedType {
+	raw, err := w.s.client.SendRequest(ctx, "typeHierarchy/"+direction, map[string]any{"item": item})
+	if err != nil {
+		return nil
+	}
+	items, err := tools.ParseTypeHierarchyItems(raw)
+	if err != nil {
+		return nil
+	}
+	out := make([]relatedType, 0, len(items))
+	for _, it := range items {
+		out = append(out, relatedType{item: it, relation: "implements"})
+	}
+	return out
+}
+
+// addEmbedded marks supertypes that item embeds and appends embedded types
+// gopls does not report (embedded structs are not assignability relations).
+func (w *typeWalker) addEmbedded(ctx context.Context, item tools.LSPTypeHierarchyItem, related []relatedType) []relatedType {
+	filePath := tools.URIToPath(item.URI)
+	code, err := os.ReadFile(filePath)
+	if err != nil {
+		return related
+	}
+	embedded, err := tools.FindEmbeddedTypes(string(code), filePath, item.Name)
+	if err != nil || len(embedded) == 0 {
+		return related
+	}
+
+	uri, err := w.openForQuery(ctx, filePath)
+	if err != nil {
+		return related
+	}
+	for _, emb := range embedded {
+		defs, err := w.s.prepareTypeHierarchy(ctx, uri, emb.Line, emb.Col)
+		if err != nil || len(defs) == 0 {
+			continue
+		}
+		def := defs[0]
+		found := false
+		for i := range related {
+			if typeItemKey(related[i].item) == typeItemKey(def) {
+				related[i].relation = "embeds"
+				found = true
+			}
+		}
+		if !found {
+			related = append(related, relatedType{item: def, relation: "embeds"})
+		}
+	}
+	return related
+}
+
+// openForQuery makes sure gopls has the on-disk file open so positions in it
+// can be queried. Files outside the workspace keep their real URI.
+func (w *typeWalker) openForQuery(ctx context.Context, filePath string) (string, error) {
+	uri := pathToURI(filePath)
+	if _, _, err := w.s.docs.OpenFile(ctx, uri, filePath); err != nil {
+		return "", err
+	}
+	return uri, nil
+}
+
+func (w *typeWalker) id(item tools.LSPTypeHierarchyItem) string {
+	key := typeItemKey(item)
+	if id, ok := w.ids[key]; ok {
+		return id
+	}
+	id := fmt.Sprintf("t%d", len(w.ids))
+	w.ids[key] = id
+	return id
+}
+
+// embedsType reports whether the declaration of item embeds a type named name.
+func embedsType(item tools.LSPTypeHierarchyItem, name string) bool {
+	filePath := tools.URIToPath(item.URI)
+	code, err := os.ReadFile(filePath)
+	if err != nil {
+		return false
+	}
+	embedded, err := tools.FindEmbeddedTypes(string(code), filePath, item.Name)
+	if err != nil {
+		return false
+	}
+	for _, emb := range embedded {
+		base := strings.TrimPrefix(emb.Name, "*")
+		if idx := strings.Index(base, "["); idx >= 0 {
+			base = base[:idx]
+		}
+		if idx := strings.LastIndex(base, "."); idx >= 0 {
+			base = base[idx+1:]
+		}
+		if base == name {
+			return true
+		}
+	}
+	return false
+}
+
+func typeItemKey(item tools.LSPTypeHierarchyItem) string {
+	return fmt.Sprintf("%s:%d:%d", item.URI, item.SelectionRange.Start.Line, item.SelectionRange.Start.Character)
+}
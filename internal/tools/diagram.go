@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RenderCallGraphMermaid renders a call graph as a Mermaid flowchart,
+// grouping nodes into one subgraph per package.
+func RenderCallGraphMermaid(nodes []CallGraphNode, edges []CallGraphEdge) string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	packages, loose := groupNodesByPackage(nodes)
+	for i, pkg := range sortedPackages(packages) {
+		fmt.Fprintf(&b, "    subgraph pkg%d[\"%s\"]\n", i, escapeMermaid(pkg))
+		for _, n := range packages[pkg] {
+			fmt.Fprintf(&b, "        %s[\"%s\"]\n", n.ID, escapeMermaid(nodeLabel(n, "<br/>")))
+		}
+		b.WriteString("    end\n")
+	}
+	for _, n := range loose {
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", n.ID, escapeMermaid(nodeLabel(n, "<br/>")))
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "    %s --> %s\n", e.From, e.To)
+	}
+	for _, n := range nodes {
+		if n.Depth == 0 {
+			fmt.Fprintf(&b, "    style %s fill:#fff3e0\n", n.ID)
+		}
+	}
+	return b.String()
+}
+
+// RenderCallGraphDOT renders a call graph in Graphviz DOT format,
+// grouping nodes into one cluster per package.
+func RenderCallGraphDOT(nodes []CallGraphNode, edges []CallGraphEdge) string {
+	var b strings.Builder
+	b.WriteString("digraph calls {\n")
+	b.WriteString("    rankdir=LR;\n")
+	b.WriteString("    node [shape=box, fontname=\"Helvetica\"];\n")
+
+	writeNode := func(indent string, n CallGraphNode) {
+		attrs := fmt.Sprintf("label=\"%s\"", escapeDOT(nodeLabel(n, "\n")))
+		if n.Depth == 0 {
+			attrs += ", style=filled, fillcolor=\"#fff3e0\""
+		}
+		fmt.Fprintf(&b, "%s%s [%s];\n", indent, n.ID, attrs)
+	}
+
+	packages, loose := groupNodesByPackage(nodes)
+	for i, pkg := range sortedPackages(packages) {
+		fmt.Fprintf(&b, "    subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "        label=\"%s\";\n", escapeDOT(pkg))
+		for _, n := range packages[pkg] {
+			writeNode("        ", n)
+		}
+		b.WriteString("    }\n")
+	}
+	for _, n := range loose {
+		writeNode("    ", n)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "    %s -> %s;\n", e.From, e.To)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func groupNodesByPackage(nodes []CallGraphNode) (map[string][]CallGraphNode, []CallGraphNode) {
+	packages := make(map[string][]CallGraphNode)
+	var loose []CallGraphNode
+	for _, n := range nodes {
+		if n.Package == "" {
+			loose = append(loose, n)
+			continue
+		}
+		packages[n.Package] = append(packages[n.Package], n)
+	}
+	return packages, loose
+}
+
+func sortedPackages(packages map[string][]CallGraphNode) []string {
+	keys := make([]string, 0, len(packages))
+	for k := range packages {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// nodeLabel returns the node name followed by its detail (package/receiver).
+func nodeLabel(n CallGraphNode, sep string) string {
+	if n.Detail == "" {
+		return n.Name
+	}
+	return n.Name + sep + n.Detail
+}
+
+func escapeMermaid(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
+
+func escapeDOT(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
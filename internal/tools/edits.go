@@ -0,0 +1,250 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspTextDocumentEdit struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Edits []lspTextEdit `json:"edits"`
+}
+
+type lspWorkspaceEdit struct {
+	Changes         map[string][]lspTextEdit `json:"changes"`
+	DocumentChanges []json.RawMessage        `json:"documentChanges"`
+}
+
+// FileEdits holds the text edits a WorkspaceEdit makes to one file.
+type FileEdits struct {
+	FilePath string
+	Edits    []lspTextEdit
+}
+
+// ParseWorkspaceEdit parses an LSP WorkspaceEdit into per-file text edits.
+// Resource operations (create/rename/delete file) are not supported.
+func ParseWorkspaceEdit(raw json.RawMessage) ([]FileEdits, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var we lspWorkspaceEdit
+	if err := json.Unmarshal(raw, &we); err != nil {
+		return nil, err
+	}
+
+	byPath := make(map[string][]lspTextEdit)
+	for uri, edits := range we.Changes {
+		path := URIToPath(uri)
+		byPath[path] = append(byPath[path], edits...)
+	}
+	for _, change := range we.DocumentChanges {
+		var probe struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(change, &probe); err == nil && probe.Kind != "" {
+			return nil, fmt.Errorf("workspace edit contains a %q file operation, which is not supported", probe.Kind)
+		}
+		var doc lspTextDocumentEdit
+		if err := json.Unmarshal(change, &doc); err != nil {
+			return nil, err
+		}
+		path := URIToPath(doc.TextDocument.URI)
+		byPath[path] = append(byPath[path], doc.Edits...)
+	}
+
+	out := make([]FileEdits, 0, len(byPath))
+	for path, edits := range byPath {
+		out = append(out, FileEdits{FilePath: path, Edits: edits})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FilePath < out[j].FilePath })
+	return out, nil
+}
+
+// ApplyEdits applies text edits to content. Edits must not overlap.
+func ApplyEdits(content string, edits []lspTextEdit) (string, error) {
+	lines := strings.SplitAfter(content, "\n")
+	offset := func(p lspPosition) (int, error) {
+		if p.Line > len(lines) {
+			return 0, fmt.Errorf("edit position %d:%d is outside the file", p.Line+1, p.Character+1)
+		}
+		start := 0
+		for i := 0; i < p.Line; i++ {
+			start += len(lines[i])
+		}
+		if p.Line == len(lines) {
+			return start, nil
+		}
+		return start + utf16ToByteOffset(strings.TrimRight(lines[p.Line], "\r\n"), p.Character), nil
+	}
+
+	type span struct {
+		start, end int
+		text       string
+	}
+	spans := make([]span, 0, len(edits))
+	for _, e := range edits {
+		start, err := offset(e.Range.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := offset(e.Range.End)
+		if err != nil {
+			return "", err
+		}
+		spans = append(spans, span{start: start, end: end, text: e.NewText})
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	last := 0
+	for _, sp := range spans {
+		if sp.start < last || sp.end < sp.start {
+			return "", fmt.Errorf("overlapping edits")
+		}
+		b.WriteString(content[last:sp.start])
+		b.WriteString(sp.text)
+		last = sp.end
+	}
+	b.WriteString(content[last:])
+	return b.String(), nil
+}
+
+// utf16ToByteOffset converts an LSP character offset (UTF-16 code units) in line to a byte offset.
+func utf16ToByteOffset(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		if r >= 0x10000 {
+			units += 2
+		} else {
+			units++
+		}
+	}
+	return len(line)
+}
+
+// byteToUTF16Offset converts a byte offset in line to an LSP character offset (UTF-16 code units).
+func byteToUTF16Offset(line string, offset int) int {
+	units := 0
+	for i, r := range line {
+		if i >= offset {
+			break
+		}
+		if r >= 0x10000 {
+			units += 2
+		} else {
+			units++
+		}
+	}
+	return units
+}
+
+// UnifiedDiff returns a unified diff between oldText and newText with 3 lines of context.
+// It is line-based and assumes the two texts differ only in localized regions,
+// which holds for LSP edits.
+func UnifiedDiff(path, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	a := strings.Split(strings.TrimSuffix(oldText, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(newText, "\n"), "\n")
+
+	// Find changed blocks by walking both sides and resynchronizing on equal lines.
+	type block struct{ a0, a1, b0, b1 int }
+	var blocks []block
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && a[i] == b[j] {
+			i++
+			j++
+			continue
+		}
+		ni, nj := resync(a, b, i, j)
+		blocks = append(blocks, block{a0: i, a1: ni, b0: j, b1: nj})
+		i, j = ni, nj
+	}
+
+	const context = 3
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- a/%s\n+++ b/%s\n", path, path)
+	for k := 0; k < len(blocks); {
+		// Merge blocks whose context windows touch.
+		first := blocks[k]
+		end := k
+		for end+1 < len(blocks) && blocks[end+1].a0-blocks[end].a1 <= 2*context {
+			end++
+		}
+		last := blocks[end]
+		hs := max(first.a0-context, 0)
+		he := min(last.a1+context, len(a))
+		offset := first.b0 - first.a0
+		hbs := hs + offset
+		hbe := he + (last.b1 - last.a1)
+
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", hs+1, he-hs, hbs+1, hbe-hbs)
+		pos := hs
+		for m := k; m <= end; m++ {
+			blk := blocks[m]
+			for ; pos < blk.a0; pos++ {
+				out.WriteString(" " + a[pos] + "\n")
+			}
+			for x := blk.a0; x < blk.a1; x++ {
+				out.WriteString("-" + a[x] + "\n")
+			}
+			for y := blk.b0; y < blk.b1; y++ {
+				out.WriteString("+" + b[y] + "\n")
+			}
+			pos = blk.a1
+		}
+		for ; pos < he; pos++ {
+			out.WriteString(" " + a[pos] + "\n")
+		}
+		k = end + 1
+	}
+	return out.String()
+}
+
+// resync finds the nearest pair of indices (ni >= i, nj >= j) where a and b agree again,
+// preferring the smallest combined distance.
+func resync(a, b []string, i, j int) (int, int) {
+	for d := 1; d <= len(a)-i+len(b)-j; d++ {
+		for x := 0; x <= d; x++ {
+			ni, nj := i+x, j+d-x
+			if ni > len(a) || nj > len(b) {
+				continue
+			}
+			if ni == len(a) && nj == len(b) {
+				return ni, nj
+			}
+			if ni < len(a) && nj < len(b) && a[ni] == b[nj] && matchesAhead(a, b, ni, nj) {
+				return ni, nj
+			}
+		}
+	}
+	return len(a), len(b)
+}
+
+// matchesAhead requires a short run of equal lines so that blank or brace-only
+// lines do not cause a premature resynchronization.
+func matchesAhead(a, b []string, i, j int) bool {
+	for k := 0; k < 3; k++ {
+		if i+k >= len(a) || j+k >= len(b) {
+			return i+k >= len(a) && j+k >= len(b)
+		}
+		if a[i+k] != b[j+k] {
+			return false
+		}
+	}
+	return true
+}
@@ -23,10 +23,13 @@ type ModuleInfo struct {
 
 // PackageInfo represents go list -json output.
 type PackageInfo struct {
-	Dir        string `json:"Dir"`
-	ImportPath string `json:"ImportPath"`
-	GoFiles    []string `json:"GoFiles"`
-	Module     *ModuleInfo `json:"Module"`
+	Dir          string      `json:"Dir"`
+	ImportPath   string      `json:"ImportPath"`
+	Name         string      `json:"Name"`
+	GoFiles      []string    `json:"GoFiles"`
+	TestGoFiles  []string    `json:"TestGoFiles"`
+	XTestGoFiles []string    `json:"XTestGoFiles"`
+	Module       *ModuleInfo `json:"Module"`
 }
 
 // ResolveImportPath resolves an import path to its directory on disk.
@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// DescribePackage summarizes the exported API of a package resolved by go list.
+// In compact mode documentation is omitted and type bodies are collapsed.
+func DescribePackage(pkg *PackageInfo, compact bool) (*DescribePackageOutput, error) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, filename := range pkg.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, filename), nil, parser.ParseComments)
+		if err != nil {
+			continue // Skip files that fail to parse
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no parseable Go files found")
+	}
+
+	p, err := doc.NewFromFiles(fset, files, pkg.ImportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	d := packageDescriber{fset: fset, compact: compact}
+	out := &DescribePackageOutput{
+		ImportPath:    pkg.ImportPath,
+		Name:          p.Name,
+		Dir:           pkg.Dir,
+		FileCount:     len(pkg.GoFiles),
+		TestFileCount: len(pkg.TestGoFiles) + len(pkg.XTestGoFiles),
+		Consts:        d.values(p.Consts),
+		Vars:          d.values(p.Vars),
+		Funcs:         d.funcs(p.Funcs),
+	}
+	if !compact {
+		out.Doc = strings.TrimSpace(p.Doc)
+	}
+	for _, t := range p.Types {
+		out.Types = append(out.Types, PackageType{
+			Name:      t.Name,
+			Signature: d.typeSignature(t),
+			Doc:       d.doc(t.Doc),
+			Consts:    d.values(t.Consts),
+			Vars:      d.values(t.Vars),
+			Funcs:     d.funcs(t.Funcs),
+			Methods:   d.funcs(t.Methods),
+		})
+	}
+	return out, nil
+}
+
+type packageDescriber struct {
+	fset    *token.FileSet
+	compact bool
+}
+
+func (d packageDescriber) doc(text string) string {
+	if d.compact {
+		return ""
+	}
+	return strings.TrimSpace(text)
+}
+
+func (d packageDescriber) values(values []*doc.Value) []PackageSymbol {
+	out := make([]PackageSymbol, 0, len(values))
+	for _, v := range values {
+		out = append(out, PackageSymbol{
+			Name:      strings.Join(v.Names, ", "),
+			Signature: formatNode(d.fset, v.Decl),
+			Doc:       d.doc(v.Doc),
+		})
+	}
+	return out
+}
+
+func (d packageDescriber) funcs(funcs []*doc.Func) []PackageSymbol {
+	out := make([]PackageSymbol, 0, len(funcs))
+	for _, f := range funcs {
+		f.Decl.Body = nil
+		out = append(out, PackageSymbol{
+			Name:      f.Name,
+			Signature: formatNode(d.fset, f.Decl),
+			Doc:       d.doc(f.Doc),
+		})
+	}
+	return out
+}
+
+// typeSignature prints a type declaration; compact mode keeps only the header,
+// e.g. "type Client struct{ ... }".
+func (d packageDescriber) typeSignature(t *doc.Type) string {
+	if !d.compact {
+		return formatNode(d.fset, t.Decl)
+	}
+	for _, spec := range t.Decl.Specs {
+		ts, ok := spec.(*ast.TypeSpec)
+		if !ok || ts.Name.Name != t.Name {
+			continue
+		}
+		switch ts.Type.(type) {
+		case *ast.StructType:
+			return "type " + t.Name + " struct{ ... }"
+		case *ast.InterfaceType:
+			return "type " + t.Name + " interface{ ... }"
+		default:
+			return "type " + formatNode(d.fset, ts)
+		}
+	}
+	return "type " + t.Name
+}
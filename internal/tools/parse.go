@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -33,7 +34,7 @@ type diagnosticReport struct {
 }
 
 type diagnosticDocSection struct {
-	Diagnostics []lspDiagnostic `json:"diagnostics"`
+	Items []lspDiagnostic `json:"items"`
 }
 
 type lspLocation struct {
@@ -42,8 +43,9 @@ type lspLocation struct {
 }
 
 type lspLocationLink struct {
-	TargetURI   string   `json:"targetUri"`
-	TargetRange lspRange `json:"targetRange"`
+	TargetURI            string    `json:"targetUri"`
+	TargetRange          lspRange  `json:"targetRange"`
+	TargetSelectionRange *lspRange `json:"targetSelectionRange"` // The name within TargetRange
 }
 
 type lspHover struct {
@@ -64,20 +66,17 @@ func ParseDiagnostics(raw json.RawMessage, uri string) ([]Diagnostic, error) {
 	}
 
 	var report diagnosticReport
-	if err := json.Unmarshal(raw, &report); err == nil {
-		if len(report.RelatedDocuments) > 0 {
-			var diags []Diagnostic
-			for docURI, section := range report.RelatedDocuments {
-				if uri != "" && docURI != uri {
-					continue
-				}
-				diags = append(diags, convertDiagnostics(section.Diagnostics)...)
+	if err := json.Unmarshal(raw, &report); err == nil && report.Kind != "" {
+		// A full report, whose items are empty for a clean file, or an
+		// "unchanged" one, which has none.
+		diags := convertDiagnostics(report.Items)
+		for docURI, section := range report.RelatedDocuments {
+			if uri != "" && docURI != uri {
+				continue
 			}
-			return diags, nil
-		}
-		if len(report.Items) > 0 {
-			return convertDiagnostics(report.Items), nil
+			diags = append(diags, convertDiagnostics(section.Items)...)
 		}
+		return diags, nil
 	}
 
 	var arr []lspDiagnostic
@@ -130,6 +129,13 @@ func ParseLocations(raw json.RawMessage) ([]Location, error) {
 		return []Location{}, nil
 	}
 
+	// Location and LocationLink arrays both decode into either type: tell
+	// them apart by their URI field.
+	var links []lspLocationLink
+	if err := json.Unmarshal(raw, &links); err == nil && len(links) > 0 && links[0].TargetURI != "" {
+		return convertLocationLinks(links), nil
+	}
+
 	var many []lspLocation
 	if err := json.Unmarshal(raw, &many); err == nil {
 		return convertLocations(many), nil
@@ -140,11 +146,6 @@ func ParseLocations(raw json.RawMessage) ([]Location, error) {
 		return convertLocations([]lspLocation{single}), nil
 	}
 
-	var links []lspLocationLink
-	if err := json.Unmarshal(raw, &links); err == nil {
-		return convertLocationLinks(links), nil
-	}
-
 	return nil, fmt.Errorf("unsupported location format")
 }
 
@@ -165,12 +166,16 @@ func convertLocations(items []lspLocation) []Location {
 func convertLocationLinks(items []lspLocationLink) []Location {
 	out := make([]Location, 0, len(items))
 	for _, loc := range items {
+		r := loc.TargetRange
+		if loc.TargetSelectionRange != nil {
+			r = *loc.TargetSelectionRange
+		}
 		out = append(out, Location{
 			FilePath: URIToPath(loc.TargetURI),
-			Line:     loc.TargetRange.Start.Line + 1,
-			Col:      loc.TargetRange.Start.Character + 1,
-			EndLine:  loc.TargetRange.End.Line + 1,
-			EndCol:   loc.TargetRange.End.Character + 1,
+			Line:     r.Start.Line + 1,
+			Col:      r.Start.Character + 1,
+			EndLine:  r.End.Line + 1,
+			EndCol:   r.End.Character + 1,
 		})
 	}
 	return out
@@ -416,3 +421,194 @@ func ConvertToLSPCallHierarchyItem(item LSPCallHierarchyItem) map[string]any {
 		},
 	}
 }
+
+// LSPCallHierarchyCall pairs a call hierarchy item with the ranges of its call sites.
+type LSPCallHierarchyCall struct {
+	Item   LSPCallHierarchyItem
+	Ranges []lspRange
+}
+
+// CallSiteLine returns the 1-based line of the first call site, or 0 if unknown.
+func (c LSPCallHierarchyCall) CallSiteLine() int {
+	if len(c.Ranges) == 0 {
+		return 0
+	}
+	return c.Ranges[0].Start.Line + 1
+}
+
+// ParseCallHierarchyIncomingCalls parses callHierarchy/incomingCalls keeping the LSP items,
+// so that callers can be expanded further.
+func ParseCallHierarchyIncomingCalls(raw json.RawMessage) ([]LSPCallHierarchyCall, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var calls []lspCallHierarchyIncomingCall
+	if err := json.Unmarshal(raw, &calls); err != nil {
+		return nil, err
+	}
+	out := make([]LSPCallHierarchyCall, 0, len(calls))
+	for _, c := range calls {
+		out = append(out, LSPCallHierarchyCall{Item: c.From, Ranges: c.FromRanges})
+	}
+	return out, nil
+}
+
+// ParseCallHierarchyOutgoingCalls parses callHierarchy/outgoingCalls keeping the LSP items,
+// so that callees can be expanded further.
+func ParseCallHierarchyOutgoingCalls(raw json.RawMessage) ([]LSPCallHierarchyCall, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var calls []lspCallHierarchyOutgoingCall
+	if err := json.Unmarshal(raw, &calls); err != nil {
+		return nil, err
+	}
+	out := make([]LSPCallHierarchyCall, 0, len(calls))
+	for _, c := range calls {
+		out = append(out, LSPCallHierarchyCall{Item: c.To, Ranges: c.ToRanges})
+	}
+	return out, nil
+}
+
+// CallHierarchyPackage extracts the package path from a gopls call hierarchy detail
+// such as "github.com/x/pkg • file.go".
+func CallHierarchyPackage(detail string) string {
+	if idx := strings.Index(detail, " • "); idx >= 0 {
+		return strings.TrimSpace(detail[:idx])
+	}
+	return strings.TrimSpace(detail)
+}
+
+// LSPTypeHierarchyItem represents an LSP TypeHierarchyItem. Data is opaque
+// server state that must be sent back unchanged in follow-up requests.
+type LSPTypeHierarchyItem struct {
+	Name           string          `json:"name"`
+	Kind           int             `json:"kind"`
+	Detail         string          `json:"detail,omitempty"`
+	URI            string          `json:"uri"`
+	Range          lspRange        `json:"range"`
+	SelectionRange lspRange        `json:"selectionRange"`
+	Data           json.RawMessage `json:"data,omitempty"`
+}
+
+// Line returns the 1-based line number of the selection range.
+func (i LSPTypeHierarchyItem) Line() int {
+	return i.SelectionRange.Start.Line + 1
+}
+
+// Col returns the 1-based column of the selection range.
+func (i LSPTypeHierarchyItem) Col() int {
+	return i.SelectionRange.Start.Character + 1
+}
+
+// ParseTypeHierarchyItems parses the result of textDocument/prepareTypeHierarchy,
+// typeHierarchy/supertypes or typeHierarchy/subtypes.
+func ParseTypeHierarchyItems(raw json.RawMessage) ([]LSPTypeHierarchyItem, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var items []LSPTypeHierarchyItem
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// LSPDocumentSymbol represents an LSP DocumentSymbol.
+type LSPDocumentSymbol struct {
+	Name           string              `json:"name"`
+	Detail         string              `json:"detail"`
+	Kind           int                 `json:"kind"`
+	Range          lspRange            `json:"range"`
+	SelectionRange lspRange            `json:"selectionRange"`
+	Children       []LSPDocumentSymbol `json:"children"`
+}
+
+// StartLine returns the 1-based first line of the symbol's full range.
+func (d LSPDocumentSymbol) StartLine() int { return d.Range.Start.Line + 1 }
+
+// EndLine returns the 1-based last line of the symbol's full range.
+func (d LSPDocumentSymbol) EndLine() int { return d.Range.End.Line + 1 }
+
+// ParseDocumentSymbols parses the result of textDocument/documentSymbol. Flat
+// SymbolInformation results are converted to childless document symbols.
+func ParseDocumentSymbols(raw json.RawMessage) ([]LSPDocumentSymbol, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var probe []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return nil, err
+	}
+	if len(probe) > 0 {
+		if _, flat := probe[0]["location"]; flat {
+			var infos []lspSymbolInformation
+			if err := json.Unmarshal(raw, &infos); err != nil {
+				return nil, err
+			}
+			out := make([]LSPDocumentSymbol, 0, len(infos))
+			for _, info := range infos {
+				out = append(out, LSPDocumentSymbol{
+					Name:           info.Name,
+					Kind:           info.Kind,
+					Range:          info.Location.Range,
+					SelectionRange: info.Location.Range,
+				})
+			}
+			return out, nil
+		}
+	}
+	var symbols []LSPDocumentSymbol
+	if err := json.Unmarshal(raw, &symbols); err != nil {
+		return nil, err
+	}
+	return symbols, nil
+}
+
+type lspCodeAction struct {
+	Title       string `json:"title"`
+	IsPreferred bool   `json:"isPreferred"`
+}
+
+// ParseCodeActionTitles returns the titles of a textDocument/codeAction
+// result (code actions or commands), preferred actions first.
+func ParseCodeActionTitles(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var actions []lspCodeAction
+	if err := json.Unmarshal(raw, &actions); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].IsPreferred && !actions[j].IsPreferred
+	})
+	titles := make([]string, 0, len(actions))
+	for _, a := range actions {
+		if a.Title != "" {
+			titles = append(titles, a.Title)
+		}
+	}
+	return titles, nil
+}
+
+// DiagnosticToLSP converts d back to an LSP Diagnostic, e.g. for the
+// context of a textDocument/codeAction request.
+func DiagnosticToLSP(d Diagnostic) map[string]any {
+	severity := map[string]int{"error": 1, "warning": 2, "info": 3, "hint": 4}[d.Severity]
+	out := map[string]any{
+		"range": map[string]any{
+			"start": map[string]any{"line": d.Line - 1, "character": d.Col - 1},
+			"end":   map[string]any{"line": d.EndLine - 1, "character": d.EndCol - 1},
+		},
+		"severity": severity,
+		"message":  d.Message,
+	}
+	if d.Source != "" {
+		out["source"] = d.Source
+	}
+	if d.Code != "" {
+		out["code"] = d.Code
+	}
+	return out
+}
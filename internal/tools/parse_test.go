@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	const uri = "file:///work/main.go"
+	diag := `{"range": {"start": {"line": 2, "character": 4}, "end": {"line": 2, "character": 7}}, "severity": 1, "message": "undefined: x"}`
+	for _, tt := range []struct {
+		name string
+		raw  string
+		want []string // Messages
+	}{
+		{"null", `null`, nil},
+		{"clean file", `{"kind": "full", "items": []}`, nil},
+		{"unchanged", `{"kind": "unchanged", "resultId": "1"}`, nil},
+		{"full report", `{"kind": "full", "items": [` + diag + `]}`, []string{"undefined: x"}},
+		{"published array", `[` + diag + `]`, []string{"undefined: x"}},
+		{"related documents", `{"kind": "full", "items": [], "relatedDocuments": {
+			"` + uri + `": {"kind": "full", "items": [` + diag + `]},
+			"file:///work/other.go": {"kind": "full", "items": [` + diag + `]}
+		}}`, []string{"undefined: x"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			diags, err := ParseDiagnostics(json.RawMessage(tt.raw), uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diags == nil {
+				t.Error("diagnostics are nil, want a (possibly empty) result")
+			}
+			if len(diags) != len(tt.want) {
+				t.Fatalf("diagnostics = %+v, want %q", diags, tt.want)
+			}
+			for i, d := range diags {
+				if d.Message != tt.want[i] {
+					t.Errorf("diagnostic %d = %q, want %q", i, d.Message, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := ParseDiagnostics(json.RawMessage(`"nope"`), uri); err == nil {
+		t.Error("string: no error")
+	}
+}
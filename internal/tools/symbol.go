@@ -1,13 +1,16 @@
 package tools
 
 import (
+	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/printer"
 	"go/token"
 	"strings"
 )
 
 // FindSymbolPosition returns the 1-based line/column for the first matching declaration of symbol.
+// Columns here and throughout this package count UTF-16 code units, as LSP does.
 func FindSymbolPosition(code, symbol string) (int, int, bool) {
 	if code == "" || symbol == "" {
 		return 0, 0, false
@@ -16,10 +19,10 @@ func FindSymbolPosition(code, symbol string) (int, int, bool) {
 	fset := token.NewFileSet()
 	file, err := parser.ParseFile(fset, "symbol.go", code, parser.ParseComments)
 	if err == nil {
-		if line, col, ok := findDeclPosition(fset, file, symbol); ok {
+		if line, col, ok := findDeclPosition(fset, code, file, symbol); ok {
 			return line, col, true
 		}
-		if line, col, ok := findFirstIdentPosition(fset, file, symbol); ok {
+		if line, col, ok := findFirstIdentPosition(fset, code, file, symbol); ok {
 			return line, col, true
 		}
 	}
@@ -27,27 +30,27 @@ func FindSymbolPosition(code, symbol string) (int, int, bool) {
 	return findSymbolInText(code, symbol)
 }
 
-func findDeclPosition(fset *token.FileSet, file *ast.File, symbol string) (int, int, bool) {
+func findDeclPosition(fset *token.FileSet, code string, file *ast.File, symbol string) (int, int, bool) {
 	for _, decl := range file.Decls {
 		switch d := decl.(type) {
 		case *ast.FuncDecl:
 			if d.Name != nil && d.Name.Name == symbol {
-				pos := fset.Position(d.Name.Pos())
-				return pos.Line, pos.Column, true
+				line, col := identPosition(fset, code, d.Name)
+				return line, col, true
 			}
 		case *ast.GenDecl:
 			for _, spec := range d.Specs {
 				switch s := spec.(type) {
 				case *ast.TypeSpec:
 					if s.Name != nil && s.Name.Name == symbol {
-						pos := fset.Position(s.Name.Pos())
-						return pos.Line, pos.Column, true
+						line, col := identPosition(fset, code, s.Name)
+						return line, col, true
 					}
 				case *ast.ValueSpec:
 					for _, name := range s.Names {
 						if name != nil && name.Name == symbol {
-							pos := fset.Position(name.Pos())
-							return pos.Line, pos.Column, true
+							line, col := identPosition(fset, code, name)
+							return line, col, true
 						}
 					}
 				}
@@ -57,7 +60,7 @@ func findDeclPosition(fset *token.FileSet, file *ast.File, symbol string) (int,
 	return 0, 0, false
 }
 
-func findFirstIdentPosition(fset *token.FileSet, file *ast.File, symbol string) (int, int, bool) {
+func findFirstIdentPosition(fset *token.FileSet, code string, file *ast.File, symbol string) (int, int, bool) {
 	var found *ast.Ident
 	ast.Inspect(file, func(n ast.Node) bool {
 		id, ok := n.(*ast.Ident)
@@ -71,8 +74,8 @@ func findFirstIdentPosition(fset *token.FileSet, file *ast.File, symbol string)
 		return true
 	})
 	if found != nil {
-		pos := fset.Position(found.Pos())
-		return pos.Line, pos.Column, true
+		line, col := identPosition(fset, code, found)
+		return line, col, true
 	}
 	return 0, 0, false
 }
@@ -111,16 +114,495 @@ func isIdentChar(b byte) bool {
 	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') || b == '_'
 }
 
+// positionFromIndex returns the 1-based line and UTF-16 column of byte index idx in code.
 func positionFromIndex(code string, idx int) (int, int) {
-	line := 1
-	col := 1
-	for i := 0; i < idx && i < len(code); i++ {
-		if code[i] == '\n' {
-			line++
-			col = 1
+	if idx > len(code) {
+		idx = len(code)
+	}
+	line := 1 + strings.Count(code[:idx], "\n")
+	start := strings.LastIndex(code[:idx], "\n") + 1
+	return line, byteToUTF16Offset(code[start:], idx-start) + 1
+}
+
+// identPosition returns the 1-based line and UTF-16 column of ident, parsed from code.
+func identPosition(fset *token.FileSet, code string, ident *ast.Ident) (int, int) {
+	return positionFromIndex(code, fset.Position(ident.Pos()).Offset)
+}
+
+// SymbolPath is a parsed symbol reference such as "Close", "Service.Close",
+// "(*Client).SendRequest", "gopls.Client.Close" or "net/http.ListenAndServe".
+type SymbolPath struct {
+	Raw        string
+	ImportPath string // Shortest import path the reference can name, when it contains slashes
+	Package    string // Package name qualifier
+	Receiver   string // Receiver or container type (methods, fields)
+	Qualifier  string // Either a package name or a receiver type ("X.Name")
+	Name       string // Bare identifier
+
+	// selector is an import path reference with receiver parentheses
+	// removed, e.g. "gopkg.in/yaml.v3.Node.Decode". Where the import path
+	// ends is only known once it is compared with a package.
+	selector string
+}
+
+// Qualified reports whether the path carries more than a bare identifier.
+func (p SymbolPath) Qualified() bool {
+	return p.ImportPath != "" || p.Package != "" || p.Receiver != "" || p.Qualifier != ""
+}
+
+// ParseSymbolPath parses a symbol reference into its components.
+func ParseSymbolPath(symbol string) (SymbolPath, error) {
+	raw := strings.TrimSpace(symbol)
+	path := SymbolPath{Raw: raw}
+	if raw == "" {
+		return path, fmt.Errorf("symbol is empty")
+	}
+
+	prefix := ""
+	rest := raw
+	if idx := strings.LastIndex(raw, "/"); idx >= 0 {
+		prefix = raw[:idx+1]
+		rest = raw[idx+1:]
+	}
+	parts := splitSymbolParts(rest)
+	for _, part := range parts {
+		if part == "" {
+			return path, fmt.Errorf("invalid symbol %q", raw)
+		}
+	}
+
+	receiver := func(part string) (string, bool) {
+		if strings.HasPrefix(part, "(") && strings.HasSuffix(part, ")") {
+			inner := strings.TrimSpace(part[1 : len(part)-1])
+			return strings.TrimPrefix(inner, "*"), true
+		}
+		return part, false
+	}
+
+	if prefix != "" {
+		// The last path element may itself contain dots (gopkg.in/yaml.v3),
+		// so Package and Receiver are left unset: MatchesWorkspaceSymbol
+		// compares the whole reference with each package's import path.
+		if len(parts) < 2 {
+			return path, fmt.Errorf("invalid symbol %q: expected import/path.Name or import/path.Type.Name", raw)
+		}
+		selector := make([]string, len(parts))
+		for i, part := range parts {
+			selector[i], _ = receiver(part)
+		}
+		path.ImportPath = prefix + parts[0]
+		path.Name = parts[len(parts)-1]
+		path.selector = prefix + strings.Join(selector, ".")
+		if strings.HasPrefix(path.Name, "(") {
+			return path, fmt.Errorf("invalid symbol %q", raw)
+		}
+		return path, nil
+	}
+
+	switch len(parts) {
+	case 1:
+		path.Name = parts[0]
+	case 2:
+		if recv, ok := receiver(parts[0]); ok {
+			path.Receiver = recv
+		} else {
+			path.Qualifier = parts[0]
+		}
+		path.Name = parts[1]
+	case 3:
+		path.Package = parts[0]
+		path.Receiver, _ = receiver(parts[1])
+		path.Name = parts[2]
+	default:
+		return path, fmt.Errorf("invalid symbol %q: too many components", raw)
+	}
+	if strings.HasPrefix(path.Name, "(") {
+		return path, fmt.Errorf("invalid symbol %q", raw)
+	}
+	return path, nil
+}
+
+// splitSymbolParts splits on dots that are not inside parentheses.
+func splitSymbolParts(s string) []string {
+	var parts []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '.':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// SymbolCandidate is a declaration that may match a symbol reference.
+type SymbolCandidate struct {
+	Name     string `json:"name"` // Qualified display name, e.g. "(*Client).Close"
+	Kind     string `json:"kind"`
+	FilePath string `json:"file_path,omitempty"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	Package  string `json:"-"`
+	Receiver string `json:"-"`
+	Ident    string `json:"-"`
+	Member   bool   `json:"-"` // Struct field or interface method
+}
+
+// AmbiguousSymbolError is returned when a symbol reference matches several declarations.
+type AmbiguousSymbolError struct {
+	Symbol     string
+	Candidates []SymbolCandidate
+}
+
+func (e *AmbiguousSymbolError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "symbol %q is ambiguous; qualify it (e.g. Type.Method or pkg.Name). Candidates:", e.Symbol)
+	for _, c := range e.Candidates {
+		fmt.Fprintf(&b, "\n- %s (%s)", c.Name, c.Kind)
+		if c.FilePath != "" {
+			fmt.Fprintf(&b, " at %s:%d", c.FilePath, c.Line)
 		} else {
-			col++
+			fmt.Fprintf(&b, " at line %d", c.Line)
+		}
+	}
+	return b.String()
+}
+
+// FindSymbolCandidates returns every declaration in code matching path, including
+// methods, struct fields and interface methods addressed through their type.
+func FindSymbolCandidates(code, filePath string, path SymbolPath) ([]SymbolCandidate, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, code, parser.ParseComments)
+	if err != nil && file == nil {
+		return nil, err
+	}
+	var out, members []SymbolCandidate
+	for _, c := range collectDeclCandidates(fset, code, file) {
+		if !path.MatchesCandidate(c) {
+			continue
+		}
+		c.FilePath = filePath
+		if c.Member && !path.Qualified() {
+			members = append(members, c)
+			continue
+		}
+		out = append(out, c)
+	}
+	// A bare name prefers top-level declarations over fields and interface
+	// methods sharing it (e.g. "Config" vs. a field "Config Config").
+	if len(out) == 0 {
+		out = members
+	}
+	return out, nil
+}
+
+// DeclarationsInFile returns every package-level declaration, method, struct field
+// and interface method declared in code.
+func DeclarationsInFile(code, filePath string) ([]SymbolCandidate, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, code, parser.ParseComments)
+	if err != nil && file == nil {
+		return nil, err
+	}
+	decls := collectDeclCandidates(fset, code, file)
+	for i := range decls {
+		decls[i].FilePath = filePath
+	}
+	return decls, nil
+}
+
+// MatchesCandidate reports whether a declaration satisfies every qualifier in the path.
+func (p SymbolPath) MatchesCandidate(c SymbolCandidate) bool {
+	if c.Ident != p.Name {
+		return false
+	}
+	if p.Package != "" && c.Package != p.Package {
+		return false
+	}
+	if p.Receiver != "" && c.Receiver != p.Receiver {
+		return false
+	}
+	if p.Qualifier != "" {
+		return c.Receiver == p.Qualifier || (c.Receiver == "" && c.Package == p.Qualifier)
+	}
+	if p.Receiver == "" && p.Package != "" && c.Receiver != "" {
+		// pkg.Name addresses package-level declarations only.
+		return false
+	}
+	return true
+}
+
+// MatchesWorkspaceSymbol reports whether a workspace/symbol result satisfies the path.
+// gopls reports methods as "Type.Method" (optionally package-qualified) and the
+// package import path as the container name.
+func (p SymbolPath) MatchesWorkspaceSymbol(sym SymbolInformation) bool {
+	name := sym.Name
+	receiver := ""
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		receiver = name[:idx]
+		name = name[idx+1:]
+		if j := strings.LastIndex(receiver, "."); j >= 0 {
+			receiver = receiver[j+1:]
+		}
+	}
+	if name != p.Name {
+		return false
+	}
+	pkgPath := sym.ContainerName
+	pkgName := packageNameOf(pkgPath)
+	if receiver == pkgName {
+		// Package-qualified function, not a method.
+		receiver = ""
+	}
+	if p.selector != "" {
+		want := pkgPath + "." + name
+		if receiver != "" {
+			want = pkgPath + "." + receiver + "." + name
+		}
+		return p.selector == want
+	}
+	c := SymbolCandidate{Ident: name, Receiver: receiver, Package: pkgName}
+	return p.MatchesCandidate(c)
+}
+
+// packageNameOf returns the conventional package name of an import path:
+// its last element without a major version, so both gopkg.in/yaml.v3 and
+// example.com/yaml/v3 give "yaml".
+func packageNameOf(importPath string) string {
+	elems := strings.Split(importPath, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && isMajorVersion(name) {
+		name = elems[len(elems)-2]
+	}
+	if idx := strings.Index(name, ".v"); idx > 0 && isMajorVersion(name[idx+1:]) {
+		name = name[:idx]
+	}
+	return name
+}
+
+// isMajorVersion reports whether elem is a major version suffix such as "v2".
+func isMajorVersion(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' {
+		return false
+	}
+	for i := 1; i < len(elem); i++ {
+		if elem[i] < '0' || elem[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func collectDeclCandidates(fset *token.FileSet, code string, file *ast.File) []SymbolCandidate {
+	pkg := ""
+	if file.Name != nil {
+		pkg = file.Name.Name
+	}
+	var out []SymbolCandidate
+	add := func(ident *ast.Ident, kind, receiver, display string, member bool) {
+		if ident == nil || ident.Name == "_" {
+			return
+		}
+		line, col := identPosition(fset, code, ident)
+		out = append(out, SymbolCandidate{
+			Name:     display,
+			Kind:     kind,
+			Line:     line,
+			Col:      col,
+			Package:  pkg,
+			Receiver: receiver,
+			Ident:    ident.Name,
+			Member:   member,
+		})
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				recv, pointer := receiverTypeName(d.Recv.List[0].Type)
+				display := recv + "." + d.Name.Name
+				if pointer {
+					display = "(*" + recv + ")." + d.Name.Name
+				}
+				add(d.Name, "Method", recv, display, false)
+				continue
+			}
+			add(d.Name, "Function", "", d.Name.Name, false)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					switch t := s.Type.(type) {
+					case *ast.StructType:
+						add(s.Name, "Struct", "", s.Name.Name, false)
+						if t.Fields == nil {
+							continue
+						}
+						for _, field := range t.Fields.List {
+							for _, name := range field.Names {
+								add(name, "Field", s.Name.Name, s.Name.Name+"."+name.Name, true)
+							}
+						}
+					case *ast.InterfaceType:
+						add(s.Name, "Interface", "", s.Name.Name, false)
+						if t.Methods == nil {
+							continue
+						}
+						for _, method := range t.Methods.List {
+							for _, name := range method.Names {
+								add(name, "Method", s.Name.Name, s.Name.Name+"."+name.Name, true)
+							}
+						}
+					default:
+						add(s.Name, "Type", "", s.Name.Name, false)
+					}
+				case *ast.ValueSpec:
+					kind := "Variable"
+					if d.Tok == token.CONST {
+						kind = "Constant"
+					}
+					for _, name := range s.Names {
+						add(name, kind, "", name.Name, false)
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// receiverTypeName returns the base type name of a method receiver and whether it is a pointer.
+func receiverTypeName(expr ast.Expr) (string, bool) {
+	pointer := false
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			pointer = true
+			expr = t.X
+		case *ast.ParenExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.Ident:
+			return t.Name, pointer
+		default:
+			return "", pointer
+		}
+	}
+}
+
+// OffsetToPosition converts a 0-based byte offset into a 1-based line and UTF-16 column.
+func OffsetToPosition(code string, offset int) (int, int, bool) {
+	if offset < 0 || offset > len(code) {
+		return 0, 0, false
+	}
+	line, col := positionFromIndex(code, offset)
+	return line, col, true
+}
+
+// IdentifierAt returns the identifier covering the 1-based line and UTF-16 column, if any.
+func IdentifierAt(code string, line, col int) string {
+	lines := strings.Split(code, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	text := lines[line-1]
+	if col < 1 || col-1 > byteToUTF16Offset(text, len(text)) {
+		return ""
+	}
+	idx := utf16ToByteOffset(text, col-1)
+	start, end := idx, idx
+	for start > 0 && isIdentChar(text[start-1]) {
+		start--
+	}
+	for end < len(text) && isIdentChar(text[end]) {
+		end++
+	}
+	return text[start:end]
+}
+
+// EmbeddedType is a type embedded in a struct or interface declaration.
+type EmbeddedType struct {
+	Name string // Type expression as written, e.g. "io.Reader" or "*Base"
+	Line int    // 1-based position of the type name identifier
+	Col  int
+}
+
+// FindEmbeddedTypes returns the types embedded by the struct or interface
+// declared as typeName in code.
+func FindEmbeddedTypes(code, filePath, typeName string) ([]EmbeddedType, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, code, 0)
+	if err != nil && file == nil {
+		return nil, err
+	}
+	var fields *ast.FieldList
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok || spec.Name == nil || spec.Name.Name != typeName {
+			return fields == nil
+		}
+		switch t := spec.Type.(type) {
+		case *ast.StructType:
+			fields = t.Fields
+		case *ast.InterfaceType:
+			fields = t.Methods
+		}
+		return false
+	})
+	if fields == nil {
+		return nil, nil
+	}
+
+	var out []EmbeddedType
+	for _, field := range fields.List {
+		if len(field.Names) > 0 {
+			continue
+		}
+		ident := embeddedTypeIdent(field.Type)
+		if ident == nil {
+			continue
+		}
+		var buf strings.Builder
+		if err := printer.Fprint(&buf, fset, field.Type); err != nil {
+			continue
+		}
+		line, col := identPosition(fset, code, ident)
+		out = append(out, EmbeddedType{Name: buf.String(), Line: line, Col: col})
+	}
+	return out, nil
+}
+
+// embeddedTypeIdent returns the identifier naming an embedded type, skipping
+// pointers, package selectors and type arguments. Type-set unions yield nil.
+func embeddedTypeIdent(expr ast.Expr) *ast.Ident {
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.ParenExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.SelectorExpr:
+			return t.Sel
+		case *ast.Ident:
+			return t
+		default:
+			return nil
 		}
 	}
-	return line, col
 }
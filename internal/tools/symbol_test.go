@@ -0,0 +1,143 @@
+package tools
+
+import "testing"
+
+// unicodeCode has a line where byte and UTF-16 columns differ: "é" is two
+// bytes and one UTF-16 unit, "😀" four bytes and two units (a surrogate pair).
+const unicodeCode = "package p\n\nvar s = \"é😀\"; var greeting = 1\n"
+
+// greetingCol is the 1-based UTF-16 column of greeting on line 3; its byte
+// column is 23.
+const greetingCol = 20
+
+func TestFindSymbolPositionUTF16(t *testing.T) {
+	line, col, ok := FindSymbolPosition(unicodeCode, "greeting")
+	if !ok || line != 3 || col != greetingCol {
+		t.Errorf("position = %d:%d (%v), want 3:%d", line, col, ok, greetingCol)
+	}
+
+	decls, err := DeclarationsInFile(unicodeCode, "p.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decls) != 2 || decls[1].Ident != "greeting" || decls[1].Col != greetingCol {
+		t.Errorf("declarations = %+v, want greeting at col %d", decls, greetingCol)
+	}
+}
+
+func TestOffsetToPositionUTF16(t *testing.T) {
+	for _, tt := range []struct {
+		offset    int
+		line, col int
+	}{
+		{0, 1, 1},
+		{11, 3, 1},
+		{20, 3, 10}, // "é"
+		{22, 3, 11}, // "😀" after "é" (2 bytes, 1 unit)
+		{26, 3, 13}, // Closing quote after "😀" (4 bytes, 2 units)
+		{33, 3, greetingCol},
+		{len(unicodeCode), 4, 1},
+	} {
+		line, col, ok := OffsetToPosition(unicodeCode, tt.offset)
+		if !ok || line != tt.line || col != tt.col {
+			t.Errorf("offset %d: position = %d:%d (%v), want %d:%d", tt.offset, line, col, ok, tt.line, tt.col)
+		}
+	}
+	if _, _, ok := OffsetToPosition(unicodeCode, len(unicodeCode)+1); ok {
+		t.Error("offset past the end is accepted")
+	}
+}
+
+func TestIdentifierAtUTF16(t *testing.T) {
+	for _, tt := range []struct {
+		line, col int
+		want      string
+	}{
+		{3, greetingCol, "greeting"},
+		{3, greetingCol + 7, "greeting"},
+		{3, 2, "var"},
+		{3, 40, ""},
+		{4, 1, ""},
+		{5, 1, ""},
+	} {
+		if got := IdentifierAt(unicodeCode, tt.line, tt.col); got != tt.want {
+			t.Errorf("%d:%d: identifier = %q, want %q", tt.line, tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestFindEmbeddedTypesUTF16(t *testing.T) {
+	code := "package p\n\ntype T struct { /* ü */ Base }\n"
+	embedded, err := FindEmbeddedTypes(code, "p.go", "T")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embedded) != 1 || embedded[0].Name != "Base" || embedded[0].Line != 3 || embedded[0].Col != 25 {
+		t.Errorf("embedded = %+v, want Base at 3:25", embedded)
+	}
+}
+
+func TestParseSymbolPath(t *testing.T) {
+	for _, tt := range []struct {
+		symbol string
+		want   SymbolPath
+	}{
+		{"Close", SymbolPath{Name: "Close"}},
+		{"Service.Close", SymbolPath{Qualifier: "Service", Name: "Close"}},
+		{"(*Client).SendRequest", SymbolPath{Receiver: "Client", Name: "SendRequest"}},
+		{"gopls.Client.Close", SymbolPath{Package: "gopls", Receiver: "Client", Name: "Close"}},
+		{"net/http.ListenAndServe", SymbolPath{ImportPath: "net/http", Name: "ListenAndServe", selector: "net/http.ListenAndServe"}},
+		{"net/http.(*Client).Do", SymbolPath{ImportPath: "net/http", Name: "Do", selector: "net/http.Client.Do"}},
+		{"gopkg.in/yaml.v3.Node", SymbolPath{ImportPath: "gopkg.in/yaml", Name: "Node", selector: "gopkg.in/yaml.v3.Node"}},
+		{"gopkg.in/yaml.v3.(*Node).Decode", SymbolPath{ImportPath: "gopkg.in/yaml", Name: "Decode", selector: "gopkg.in/yaml.v3.Node.Decode"}},
+	} {
+		got, err := ParseSymbolPath(tt.symbol)
+		tt.want.Raw = tt.symbol
+		if err != nil {
+			t.Errorf("%s: %v", tt.symbol, err)
+		} else if got != tt.want {
+			t.Errorf("%s: path = %+v, want %+v", tt.symbol, got, tt.want)
+		}
+	}
+	for _, symbol := range []string{"", "a..b", "net/http", "net/http.", "net/http.Client.(Do)", "a.b.c.d"} {
+		if _, err := ParseSymbolPath(symbol); err == nil {
+			t.Errorf("%q: no error", symbol)
+		}
+	}
+}
+
+func TestMatchesWorkspaceSymbol(t *testing.T) {
+	yamlNode := SymbolInformation{Name: "Node", ContainerName: "gopkg.in/yaml.v3"}
+	yamlDecode := SymbolInformation{Name: "Node.Decode", ContainerName: "gopkg.in/yaml.v3"}
+	// Node in the package gopkg.in/yaml, the shortest reading of gopkg.in/yaml.v3.Node.
+	otherNode := SymbolInformation{Name: "Node", ContainerName: "gopkg.in/yaml"}
+	for _, tt := range []struct {
+		symbol string
+		sym    SymbolInformation
+		want   bool
+	}{
+		{"gopkg.in/yaml.v3.Node", yamlNode, true},
+		{"gopkg.in/yaml.v3.Node", otherNode, false},
+		// Method Node of a type v3 in gopkg.in/yaml: truly ambiguous, so
+		// both match and resolving the symbol lists both.
+		{"gopkg.in/yaml.v3.Node", SymbolInformation{Name: "v3.Node", ContainerName: "gopkg.in/yaml"}, true},
+		{"gopkg.in/yaml.v3.Node", SymbolInformation{Name: "yaml.Node", ContainerName: "gopkg.in/yaml.v3"}, true},
+		{"gopkg.in/yaml.v3.(*Node).Decode", yamlDecode, true},
+		{"gopkg.in/yaml.v3.Decode", yamlDecode, false},
+		{"gopkg.in/yaml.v3.Node.Decode", SymbolInformation{Name: "Decode", ContainerName: "gopkg.in/yaml.v3.Node"}, true},
+		{"gopkg.in/yaml.v3.Node.Decode", SymbolInformation{Name: "Decode", ContainerName: "gopkg.in/yaml.v3"}, false},
+		{"net/http.Get", SymbolInformation{Name: "Get", ContainerName: "net/http"}, true},
+		{"net/http.Get", SymbolInformation{Name: "Client.Get", ContainerName: "net/http"}, false},
+		{"yaml.Node", yamlNode, true},
+		{"example.com/yaml/v3.Marshal", SymbolInformation{Name: "yaml.Marshal", ContainerName: "example.com/yaml/v3"}, true},
+		{"Node.Decode", yamlDecode, true},
+	} {
+		path, err := ParseSymbolPath(tt.symbol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := path.MatchesWorkspaceSymbol(tt.sym); got != tt.want {
+			t.Errorf("%s vs %s %s: match = %v, want %v", tt.symbol, tt.sym.ContainerName, tt.sym.Name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package tools
+
+// Target methods report the workspace directory and the file or package
+// path a tool call names, so the server can pick the gopls session to run
+// it in. Either may be empty.
+
+func (in AnalyzeCodeInput) Target() (workspace, path string) { return in.Workspace, in.FilePath }
+
+func (in SearchSymbolsInput) Target() (workspace, path string) { return in.Workspace, "" }
+
+func (in ExplainSymbolInput) Target() (workspace, path string) { return in.Workspace, in.FilePath }
+
+func (in ListSymbolsInput) Target() (workspace, path string) { return in.Workspace, in.FilePath }
+
+func (in GetCallHierarchyInput) Target() (workspace, path string) { return in.Workspace, in.FilePath }
+
+func (in ExplainImportInput) Target() (workspace, path string) { return in.Workspace, "" }
+
+func (in FindImplementationsInput) Target() (workspace, path string) {
+	return in.Workspace, in.FilePath
+}
+
+func (in GetTypeHierarchyInput) Target() (workspace, path string) { return in.Workspace, in.FilePath }
+
+func (in DescribePackageInput) Target() (workspace, path string) { return in.Workspace, in.Package }
+
+func (in RenameSymbolInput) Target() (workspace, path string) { return in.Workspace, in.FilePath }
+
+func (in GetDiagnosticsInput) Target() (workspace, path string) {
+	if in.FilePath != "" {
+		return in.Workspace, in.FilePath
+	}
+	return in.Workspace, in.Package
+}
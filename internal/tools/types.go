@@ -5,6 +5,8 @@ type AnalyzeCodeInput struct {
 	Code            string `json:"code" jsonschema:"Go source code to analyze"`
 	FilePath        string `json:"file_path" jsonschema:"File path (absolute or workspace-relative). Used for module context resolution."`
 	IncludeWarnings bool   `json:"include_warnings,omitempty" jsonschema:"Include warnings/info/hints in addition to errors. Default: false (errors only)."`
+	WaitMs          int    `json:"wait_ms,omitempty" jsonschema:"Maximum time in milliseconds to wait for gopls to analyze the code. Default: 3000."`
+	Workspace       string `json:"workspace,omitempty" jsonschema:"Absolute directory of the Go workspace/module to run in, for code outside the current workspace. Default: inferred from an absolute file path, otherwise the current workspace."`
 }
 
 type Diagnostic struct {
@@ -22,6 +24,7 @@ type Diagnostic struct {
 type AnalyzeCodeOutput struct {
 	FilePath    string       `json:"file_path"`
 	Diagnostics []Diagnostic `json:"diagnostics"`
+	Notice      string       `json:"notice,omitempty"` // Set when gopls did not publish diagnostics in time
 }
 
 // GoToDefinitionInput for go_to_definition.
@@ -29,7 +32,7 @@ type GoToDefinitionInput struct {
 	FilePath string `json:"file_path" jsonschema:"File path (absolute or workspace-relative) where the symbol is located."`
 	Symbol   string `json:"symbol,omitempty" jsonschema:"Symbol name (function/type/variable) to find. Recommended: simpler than specifying line/col."`
 	Line     int    `json:"line,omitempty" jsonschema:"1-based line number. Required if symbol is not provided."`
-	Col      int    `json:"col,omitempty" jsonschema:"1-based column number. Required if symbol is not provided."`
+	Col      int    `json:"col,omitempty" jsonschema:"1-based column in UTF-16 code units, as reported in tool results (the byte column on ASCII lines). Required if symbol is not provided."`
 	Code     string `json:"code,omitempty" jsonschema:"Go source code. Only needed if file doesn't exist on disk (e.g. unsaved buffer)."`
 	UseDisk  bool   `json:"use_disk,omitempty" jsonschema:"Deprecated: file is now read from disk by default. This field is ignored."`
 }
@@ -51,7 +54,7 @@ type FindReferencesInput struct {
 	FilePath           string `json:"file_path" jsonschema:"File path (absolute or workspace-relative) where the symbol is located."`
 	Symbol             string `json:"symbol,omitempty" jsonschema:"Symbol name (function/type/variable) to find. Recommended: simpler than specifying line/col."`
 	Line               int    `json:"line,omitempty" jsonschema:"1-based line number. Required if symbol is not provided."`
-	Col                int    `json:"col,omitempty" jsonschema:"1-based column number. Required if symbol is not provided."`
+	Col                int    `json:"col,omitempty" jsonschema:"1-based column in UTF-16 code units, as reported in tool results (the byte column on ASCII lines). Required if symbol is not provided."`
 	Code               string `json:"code,omitempty" jsonschema:"Go source code. Only needed if file doesn't exist on disk (e.g. unsaved buffer)."`
 	UseDisk            bool   `json:"use_disk,omitempty" jsonschema:"Deprecated: file is now read from disk by default. This field is ignored."`
 	IncludeDeclaration bool   `json:"include_declaration,omitempty" jsonschema:"Include the symbol declaration in results. Default: false."`
@@ -70,6 +73,8 @@ type FindReferencesOutput struct {
 type SearchSymbolsInput struct {
 	Query           string `json:"query" jsonschema:"Symbol name or pattern to search (e.g. 'Handler' or 'New*')."`
 	IncludeExternal bool   `json:"include_external,omitempty" jsonschema:"Include symbols from stdlib and dependencies. Default: false (workspace only)."`
+	NoIndexWait     bool   `json:"no_index_wait,omitempty" jsonschema:"Answer at once instead of waiting (up to 10s by default) for gopls to finish loading the workspace; results may then be incomplete. Default: false."`
+	Workspace       string `json:"workspace,omitempty" jsonschema:"Absolute directory of the Go workspace/module to run in, for code outside the current workspace. Default: the current workspace."`
 }
 
 type SymbolInformation struct {
@@ -83,6 +88,7 @@ type SymbolInformation struct {
 
 type SearchSymbolsOutput struct {
 	Symbols []SymbolInformation `json:"symbols"`
+	Notice  string              `json:"notice,omitempty"` // Set while gopls is still indexing
 }
 
 // GetHoverInput for get_hover.
@@ -90,7 +96,7 @@ type GetHoverInput struct {
 	FilePath string `json:"file_path" jsonschema:"File path (absolute or workspace-relative) where the symbol is located."`
 	Symbol   string `json:"symbol,omitempty" jsonschema:"Symbol name (function/type/variable) to find. Recommended: simpler than specifying line/col."`
 	Line     int    `json:"line,omitempty" jsonschema:"1-based line number. Required if symbol is not provided."`
-	Col      int    `json:"col,omitempty" jsonschema:"1-based column number. Required if symbol is not provided."`
+	Col      int    `json:"col,omitempty" jsonschema:"1-based column in UTF-16 code units, as reported in tool results (the byte column on ASCII lines). Required if symbol is not provided."`
 	Code     string `json:"code,omitempty" jsonschema:"Go source code. Only needed if file doesn't exist on disk (e.g. unsaved buffer)."`
 	UseDisk  bool   `json:"use_disk,omitempty" jsonschema:"Deprecated: file is now read from disk by default. This field is ignored."`
 }
@@ -102,11 +108,16 @@ type GetHoverOutput struct {
 
 // ExplainSymbolInput for explain_symbol.
 type ExplainSymbolInput struct {
-	FilePath          string `json:"file_path" jsonschema:"File path (absolute or workspace-relative) where the symbol is located."`
-	Symbol            string `json:"symbol" jsonschema:"Symbol name (function/type/variable/method) to explain."`
+	FilePath          string `json:"file_path,omitempty" jsonschema:"File path (absolute or workspace-relative) where the symbol is located. Optional when symbol is qualified."`
+	Symbol            string `json:"symbol,omitempty" jsonschema:"Symbol name or name path to explain, e.g. 'Close', 'Service.Close', '(*Client).SendRequest', 'gopls.Client.Close', 'net/http.ListenAndServe'. Required unless line/col or offset is given."`
+	Line              int    `json:"line,omitempty" jsonschema:"1-based line number of the identifier. Use with col instead of symbol (works for locals and struct fields)."`
+	Col               int    `json:"col,omitempty" jsonschema:"1-based column of the identifier in UTF-16 code units, as reported in tool results (the byte column on ASCII lines). Required with line."`
+	Offset            *int   `json:"offset,omitempty" jsonschema:"0-based byte offset of the identifier in the file. Alternative to line/col."`
 	IncludeSource     bool   `json:"include_source,omitempty" jsonschema:"Include the source code of the symbol definition. Default: true."`
 	IncludeReferences bool   `json:"include_references,omitempty" jsonschema:"Include references to this symbol. Default: true."`
 	MaxReferences     int    `json:"max_references,omitempty" jsonschema:"Maximum number of references to return. Default: 10."`
+	NoIndexWait       bool   `json:"no_index_wait,omitempty" jsonschema:"Answer at once instead of waiting (up to 10s by default) for gopls to finish loading the workspace; results may then be incomplete. Default: false."`
+	Workspace         string `json:"workspace,omitempty" jsonschema:"Absolute directory of the Go workspace/module to run in, for code outside the current workspace. Default: inferred from an absolute file path, otherwise the current workspace."`
 }
 
 // ReferenceContext contains a reference with surrounding context.
@@ -127,12 +138,14 @@ type ExplainSymbolOutput struct {
 	DefinedAt       *Location          `json:"defined_at,omitempty"`       // Where the symbol is defined
 	ReferencesCount int                `json:"references_count,omitempty"` // Total number of references
 	References      []ReferenceContext `json:"references,omitempty"`       // Sample references with context
+	Notice          string             `json:"notice,omitempty"`           // Set while gopls is still indexing
 }
 
 // ExplainImportInput for explain_import.
 type ExplainImportInput struct {
 	ImportPath string `json:"import_path" jsonschema:"Go import path (e.g. 'github.com/xxx/idl/user' or 'encoding/json')."`
 	Symbol     string `json:"symbol" jsonschema:"Type or function name to explain (e.g. 'GetUserInfoRequest')."`
+	Workspace  string `json:"workspace,omitempty" jsonschema:"Absolute directory of the Go workspace/module to run in, for code outside the current workspace. Default: the current workspace."`
 }
 
 // FieldInfo represents a struct field.
@@ -147,8 +160,8 @@ type FieldInfo struct {
 type ExplainImportOutput struct {
 	ImportPath string      `json:"import_path"`
 	Symbol     string      `json:"symbol"`
-	Kind       string      `json:"kind"`       // Struct, Interface, Function, Type, Const, Var
-	Signature  string      `json:"signature"`  // Full type definition
+	Kind       string      `json:"kind"`      // Struct, Interface, Function, Type, Const, Var
+	Signature  string      `json:"signature"` // Full type definition
 	Doc        string      `json:"doc,omitempty"`
 	Fields     []FieldInfo `json:"fields,omitempty"`  // For structs
 	Methods    []string    `json:"methods,omitempty"` // Method signatures
@@ -158,29 +171,280 @@ type ExplainImportOutput struct {
 
 // GetCallHierarchyInput for get_call_hierarchy.
 type GetCallHierarchyInput struct {
-	FilePath  string `json:"file_path" jsonschema:"File path where the function/method is located."`
-	Symbol    string `json:"symbol" jsonschema:"Function or method name to analyze."`
-	Direction string `json:"direction,omitempty" jsonschema:"Call direction: 'incoming' (callers), 'outgoing' (callees), or 'both'. Default: 'both'."`
-	Depth     int    `json:"depth,omitempty" jsonschema:"Maximum depth to traverse. Default: 1 (direct calls only)."`
+	FilePath     string `json:"file_path,omitempty" jsonschema:"File path where the function/method is located. Optional when symbol is qualified."`
+	Symbol       string `json:"symbol,omitempty" jsonschema:"Function or method name to analyze, optionally qualified (e.g. 'Service.Close', '(*Client).SendRequest'). Required unless line/col or offset is given."`
+	Line         int    `json:"line,omitempty" jsonschema:"1-based line number of the function/method name or a call to it. Use with col instead of symbol."`
+	Col          int    `json:"col,omitempty" jsonschema:"1-based column in UTF-16 code units, as reported in tool results (the byte column on ASCII lines). Required with line."`
+	Offset       *int   `json:"offset,omitempty" jsonschema:"0-based byte offset in the file. Alternative to line/col."`
+	Direction    string `json:"direction,omitempty" jsonschema:"Call direction: 'incoming' (callers), 'outgoing' (callees), or 'both'. Default: 'both'."`
+	Depth        int    `json:"depth,omitempty" jsonschema:"Maximum depth to traverse. Default: 1 (direct calls only). Max: 5."`
+	MaxFanout    int    `json:"max_fanout,omitempty" jsonschema:"Maximum number of calls followed per function at each level. Default: 20."`
+	MaxNodes     int    `json:"max_nodes,omitempty" jsonschema:"Maximum number of functions in the call graph. Default: 200."`
+	SamePackage  bool   `json:"same_package,omitempty" jsonschema:"Do not expand functions outside the package of the starting symbol. Default: false."`
+	ExcludeTests bool   `json:"exclude_tests,omitempty" jsonschema:"Skip callers/callees defined in _test.go files. Default: false."`
+	Format       string `json:"format,omitempty" jsonschema:"Output format: 'json' (default), 'mermaid' (flowchart) or 'dot' (Graphviz). Diagrams are clustered by package."`
+	NoIndexWait  bool   `json:"no_index_wait,omitempty" jsonschema:"Answer at once instead of waiting (up to 10s by default) for gopls to finish loading the workspace; results may then be incomplete. Default: false."`
+	Workspace    string `json:"workspace,omitempty" jsonschema:"Absolute directory of the Go workspace/module to run in, for code outside the current workspace. Default: inferred from an absolute file path, otherwise the current workspace."`
 }
 
 // CallHierarchyItem represents a function/method in the call hierarchy.
 type CallHierarchyItem struct {
-	Name     string    `json:"name"`
-	Kind     string    `json:"kind"`
-	FilePath string    `json:"file_path"`
-	Line     int       `json:"line"`
-	Col      int       `json:"col"`
-	Detail   string    `json:"detail,omitempty"` // Package or receiver type
-	Context  string    `json:"context,omitempty"` // The call site code
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	FilePath string `json:"file_path"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	Detail   string `json:"detail,omitempty"`  // Package or receiver type
+	Context  string `json:"context,omitempty"` // The call site code
 }
 
 // GetCallHierarchyOutput contains the call hierarchy for a symbol.
 type GetCallHierarchyOutput struct {
-	Name     string              `json:"name"`
-	Kind     string              `json:"kind"`
-	FilePath string              `json:"file_path"`
-	Line     int                 `json:"line"`
-	Incoming []CallHierarchyItem `json:"incoming,omitempty"` // Functions that call this
-	Outgoing []CallHierarchyItem `json:"outgoing,omitempty"` // Functions called by this
+	Name      string              `json:"name"`
+	Kind      string              `json:"kind"`
+	FilePath  string              `json:"file_path"`
+	Line      int                 `json:"line"`
+	Incoming  []CallHierarchyItem `json:"incoming,omitempty"`  // Functions that call this
+	Outgoing  []CallHierarchyItem `json:"outgoing,omitempty"`  // Functions called by this
+	Nodes     []CallGraphNode     `json:"nodes,omitempty"`     // All functions reached when depth > 1
+	Edges     []CallGraphEdge     `json:"edges,omitempty"`     // Caller → callee relations when depth > 1
+	Truncated bool                `json:"truncated,omitempty"` // Traversal stopped early because of max_fanout/max_nodes
+	Diagram   string              `json:"diagram,omitempty"`   // Mermaid or DOT text when format is set
+	Notice    string              `json:"notice,omitempty"`    // Set while gopls is still indexing
+}
+
+// CallGraphNode is a function/method in a multi-level call graph.
+type CallGraphNode struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	FilePath string `json:"file_path"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	Detail   string `json:"detail,omitempty"`  // Package or receiver type
+	Package  string `json:"package,omitempty"` // Package path derived from detail
+	Depth    int    `json:"depth"`             // Distance from the starting symbol
+}
+
+// CallGraphEdge is a call from one node to another.
+type CallGraphEdge struct {
+	From    string `json:"from"`              // Caller node ID
+	To      string `json:"to"`                // Callee node ID
+	Line    int    `json:"line,omitempty"`    // Line of the call site in the caller
+	Context string `json:"context,omitempty"` // The call site code
+}
+
+// GetDiagnosticsInput for get_diagnostics.
+type GetDiagnosticsInput struct {
+	FilePath    string `json:"file_path,omitempty" jsonschema:"File path (absolute or workspace-relative) to check. Takes precedence over package."`
+	Package     string `json:"package,omitempty" jsonschema:"Package directory (absolute or workspace-relative) or import path to check. Omit both file_path and package to check the whole workspace."`
+	MinSeverity string `json:"min_severity,omitempty" jsonschema:"Lowest severity to report: 'error', 'warning', 'info' or 'hint'. Default: 'error'."`
+	WaitMs      int    `json:"wait_ms,omitempty" jsonschema:"Maximum time in milliseconds to wait for gopls to finish analysis. Default: 3000."`
+	MaxResults  int    `json:"max_results,omitempty" jsonschema:"Maximum number of diagnostics to return. Default: 100."`
+	Code        string `json:"code,omitempty" jsonschema:"Unsaved content to check instead of the file on disk: the content of file_path (which need not exist), or with package an extra file of that package. Nothing is written to disk."`
+	NoIndexWait bool   `json:"no_index_wait,omitempty" jsonschema:"For the whole workspace: answer at once instead of waiting (up to 10s by default) for gopls to finish loading the workspace; results may then be incomplete. Default: false."`
+	Workspace   string `json:"workspace,omitempty" jsonschema:"Absolute directory of the Go workspace/module to run in, for code outside the current workspace. Default: inferred from an absolute file path, otherwise the current workspace."`
+}
+
+// FileDiagnostics groups diagnostics reported for one file.
+type FileDiagnostics struct {
+	FilePath    string       `json:"file_path"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// GetDiagnosticsOutput contains diagnostics grouped by file.
+type GetDiagnosticsOutput struct {
+	Scope        string            `json:"scope"` // file, package or workspace
+	Files        []FileDiagnostics `json:"files"`
+	ErrorCount   int               `json:"error_count"`
+	WarningCount int               `json:"warning_count"`
+	Truncated    bool              `json:"truncated,omitempty"` // More diagnostics exist than max_results
+	Notice       string            `json:"notice,omitempty"`    // Set while gopls is still indexing
+}
+
+// FindImplementationsInput for find_implementations.
+type FindImplementationsInput struct {
+	FilePath        string `json:"file_path,omitempty" jsonschema:"File path (absolute or workspace-relative) where the interface/type/method is located. Optional when symbol is qualified."`
+	Symbol          string `json:"symbol,omitempty" jsonschema:"Interface, type or method name (qualified names like 'Store.Get' are supported). Required unless line/col or offset is given."`
+	Line            int    `json:"line,omitempty" jsonschema:"1-based line number of the identifier. Use with col instead of symbol."`
+	Col             int    `json:"col,omitempty" jsonschema:"1-based column in UTF-16 code units, as reported in tool results (the byte column on ASCII lines). Required with line."`
+	Offset          *int   `json:"offset,omitempty" jsonschema:"0-based byte offset in the file. Alternative to line/col."`
+	IncludeExternal bool   `json:"include_external,omitempty" jsonschema:"Include results from stdlib and dependencies. Default: false (workspace only)."`
+	MaxResults      int    `json:"max_results,omitempty" jsonschema:"Maximum number of results to return. Default: 50."`
+	NoIndexWait     bool   `json:"no_index_wait,omitempty" jsonschema:"Answer at once instead of waiting (up to 10s by default) for gopls to finish loading the workspace; results may then be incomplete. Default: false."`
+	Workspace       string `json:"workspace,omitempty" jsonschema:"Absolute directory of the Go workspace/module to run in, for code outside the current workspace. Default: inferred from an absolute file path, otherwise the current workspace."`
+}
+
+// ImplementationItem is a type or method related by interface satisfaction.
+type ImplementationItem struct {
+	Name     string `json:"name"` // e.g. "Client" or "(*Client).Close"
+	Kind     string `json:"kind"`
+	FilePath string `json:"file_path"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	Snippet  string `json:"snippet,omitempty"` // The declaration line
+}
+
+// FindImplementationsOutput lists implementations of an interface, or the
+// interfaces satisfied by a concrete type.
+type FindImplementationsOutput struct {
+	Name            string               `json:"name"`
+	Kind            string               `json:"kind"`
+	Direction       string               `json:"direction"` // "implementations" (interface → types) or "interfaces" (type → interfaces)
+	Implementations []ImplementationItem `json:"implementations"`
+	Total           int                  `json:"total"`
+	Notice          string               `json:"notice,omitempty"` // Set while gopls is still indexing
+}
+
+// GetTypeHierarchyInput for get_type_hierarchy.
+type GetTypeHierarchyInput struct {
+	FilePath    string `json:"file_path,omitempty" jsonschema:"File path where the type is located. Optional when symbol is qualified."`
+	Symbol      string `json:"symbol,omitempty" jsonschema:"Type or interface name (qualified names supported). Required unless line/col or offset is given."`
+	Line        int    `json:"line,omitempty" jsonschema:"1-based line number of the type name. Use with col instead of symbol."`
+	Col         int    `json:"col,omitempty" jsonschema:"1-based column in UTF-16 code units, as reported in tool results (the byte column on ASCII lines). Required with line."`
+	Offset      *int   `json:"offset,omitempty" jsonschema:"0-based byte offset in the file. Alternative to line/col."`
+	Direction   string `json:"direction,omitempty" jsonschema:"'supertypes' (interfaces it satisfies, types it embeds), 'subtypes' (implementations), or 'both'. Default: 'both'."`
+	Depth       int    `json:"depth,omitempty" jsonschema:"Maximum depth to traverse. Default: 1. Max: 5."`
+	MaxResults  int    `json:"max_results,omitempty" jsonschema:"Maximum number of types per direction. Default: 100."`
+	NoIndexWait bool   `json:"no_index_wait,omitempty" jsonschema:"Answer at once instead of waiting (up to 10s by default) for gopls to finish loading the workspace; results may then be incomplete. Default: false."`
+	Workspace   string `json:"workspace,omitempty" jsonschema:"Absolute directory of the Go workspace/module to run in, for code outside the current workspace. Default: inferred from an absolute file path, otherwise the current workspace."`
+}
+
+// TypeHierarchyItem is a type reached while walking the type hierarchy.
+type TypeHierarchyItem struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	FilePath string `json:"file_path"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	Detail   string `json:"detail,omitempty"`
+	Relation string `json:"relation"` // "embeds" or "implements", read as subtype → supertype
+	Parent   string `json:"parent"`   // ID of the type this one was reached from
+	Depth    int    `json:"depth"`
+}
+
+// GetTypeHierarchyOutput contains the type hierarchy for a type.
+type GetTypeHierarchyOutput struct {
+	ID         string              `json:"id"` // Always "t0"; referenced by parent fields
+	Name       string              `json:"name"`
+	Kind       string              `json:"kind"`
+	FilePath   string              `json:"file_path"`
+	Line       int                 `json:"line"`
+	Supertypes []TypeHierarchyItem `json:"supertypes,omitempty"` // Interfaces satisfied and types embedded
+	Subtypes   []TypeHierarchyItem `json:"subtypes,omitempty"`   // Types implementing or embedding this one
+	Truncated  bool                `json:"truncated,omitempty"`
+	Notice     string              `json:"notice,omitempty"` // Set while gopls is still indexing
+}
+
+// ListSymbolsInput for list_symbols.
+type ListSymbolsInput struct {
+	FilePath     string   `json:"file_path" jsonschema:"File path (absolute or workspace-relative) to outline."`
+	IncludeKinds []string `json:"include_kinds,omitempty" jsonschema:"Only return these kinds, e.g. ['struct', 'interface', 'function', 'method', 'field', 'constant', 'variable']."`
+	ExcludeKinds []string `json:"exclude_kinds,omitempty" jsonschema:"Do not return these kinds."`
+	IncludeBody  bool     `json:"include_body,omitempty" jsonschema:"Include the source of each symbol. Default: false."`
+	MaxResults   int      `json:"max_results,omitempty" jsonschema:"Maximum number of symbols (including children) to return. Default: 100."`
+	Workspace    string   `json:"workspace,omitempty" jsonschema:"Absolute directory of the Go workspace/module to run in, for code outside the current workspace. Default: inferred from an absolute file path, otherwise the current workspace."`
+}
+
+// OutlineMember is a field or method nested under a type in a file outline.
+type OutlineMember struct {
+	Name      string `json:"name"`
+	NamePath  string `json:"name_path"` // e.g. "Config.Host" or "Config.Run"
+	Kind      string `json:"kind"`
+	Line      int    `json:"line"`
+	Col       int    `json:"col"`
+	EndLine   int    `json:"end_line"`
+	Signature string `json:"signature,omitempty"` // Declaration line
+	Body      string `json:"body,omitempty"`
+}
+
+// OutlineSymbol is a top-level declaration in a file outline.
+type OutlineSymbol struct {
+	Name      string          `json:"name"`
+	NamePath  string          `json:"name_path"`
+	Kind      string          `json:"kind"`
+	Line      int             `json:"line"`
+	Col       int             `json:"col"`
+	EndLine   int             `json:"end_line"`
+	Signature string          `json:"signature,omitempty"` // Declaration line
+	Receiver  string          `json:"receiver,omitempty"`  // Containing type of a member listed at top level
+	Body      string          `json:"body,omitempty"`
+	Children  []OutlineMember `json:"children,omitempty"` // Fields and methods of types
+}
+
+// ListSymbolsOutput is the outline of a file.
+type ListSymbolsOutput struct {
+	FilePath   string          `json:"file_path"`
+	Symbols    []OutlineSymbol `json:"symbols"`
+	TotalCount int             `json:"total_count"`
+	Truncated  bool            `json:"truncated,omitempty"`
+}
+
+// DescribePackageInput for describe_package.
+type DescribePackageInput struct {
+	Package   string `json:"package" jsonschema:"Import path (e.g. 'encoding/json') or package directory (absolute or workspace-relative)."`
+	Compact   bool   `json:"compact,omitempty" jsonschema:"Only list signatures: no documentation and type bodies collapsed. Default: false."`
+	Workspace string `json:"workspace,omitempty" jsonschema:"Absolute directory of the Go workspace/module to run in, for code outside the current workspace. Default: inferred from an absolute file path, otherwise the current workspace."`
+}
+
+// PackageSymbol is an exported declaration in a package summary.
+type PackageSymbol struct {
+	Name      string `json:"name"`      // Comma-separated for grouped const/var blocks
+	Signature string `json:"signature"` // Declaration without function bodies
+	Doc       string `json:"doc,omitempty"`
+}
+
+// PackageType is an exported type with its associated declarations, grouped like `go doc -all`.
+type PackageType struct {
+	Name      string          `json:"name"`
+	Signature string          `json:"signature"`
+	Doc       string          `json:"doc,omitempty"`
+	Consts    []PackageSymbol `json:"consts,omitempty"`  // Typed constants
+	Vars      []PackageSymbol `json:"vars,omitempty"`    // Typed variables
+	Funcs     []PackageSymbol `json:"funcs,omitempty"`   // Constructors returning the type
+	Methods   []PackageSymbol `json:"methods,omitempty"` // Method set
+}
+
+// DescribePackageOutput summarizes the exported API of a package.
+type DescribePackageOutput struct {
+	ImportPath    string          `json:"import_path"`
+	Name          string          `json:"name"`
+	Dir           string          `json:"dir"`
+	Doc           string          `json:"doc,omitempty"`
+	FileCount     int             `json:"file_count"`
+	TestFileCount int             `json:"test_file_count"`
+	Consts        []PackageSymbol `json:"consts,omitempty"`
+	Vars          []PackageSymbol `json:"vars,omitempty"`
+	Funcs         []PackageSymbol `json:"funcs,omitempty"`
+	Types         []PackageType   `json:"types,omitempty"`
+}
+
+// RenameSymbolInput for rename_symbol.
+type RenameSymbolInput struct {
+	FilePath  string `json:"file_path,omitempty" jsonschema:"File path (absolute or workspace-relative) where the symbol is located. Optional when symbol is qualified."`
+	Symbol    string `json:"symbol,omitempty" jsonschema:"Symbol name or name path to rename, e.g. 'Close', 'Service.Close', '(*Client).SendRequest'. Required unless line/col or offset is given."`
+	Line      int    `json:"line,omitempty" jsonschema:"1-based line number of the identifier. Use with col instead of symbol (works for locals and struct fields)."`
+	Col       int    `json:"col,omitempty" jsonschema:"1-based column of the identifier in UTF-16 code units, as reported in tool results (the byte column on ASCII lines). Required with line."`
+	Offset    *int   `json:"offset,omitempty" jsonschema:"0-based byte offset of the identifier in the file. Alternative to line/col."`
+	NewName   string `json:"new_name" jsonschema:"New identifier name."`
+	Apply     bool   `json:"apply,omitempty" jsonschema:"Write the changes to disk. Default: false (dry run, only return diffs)."`
+	Workspace string `json:"workspace,omitempty" jsonschema:"Absolute directory of the Go workspace/module to run in, for code outside the current workspace. Default: inferred from an absolute file path, otherwise the current workspace."`
+}
+
+// RenameFileChange is the change a rename makes to one file.
+type RenameFileChange struct {
+	FilePath string `json:"file_path"`
+	Edits    int    `json:"edits"`
+	Diff     string `json:"diff"` // Unified diff
+}
+
+// RenameSymbolOutput for rename_symbol.
+type RenameSymbolOutput struct {
+	OldName   string             `json:"old_name"`
+	NewName   string             `json:"new_name"`
+	Applied   bool               `json:"applied"`
+	FileCount int                `json:"file_count"`
+	EditCount int                `json:"edit_count"`
+	Files     []RenameFileChange `json:"files"`
 }
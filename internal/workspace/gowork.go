@@ -0,0 +1,125 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ParseGoWork returns the absolute module directories named by the use
+// directives of the go.work file at path.
+func ParseGoWork(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Dir(path)
+	var dirs []string
+	inBlock := false
+	for i, line := range strings.Split(string(data), "\n") {
+		if j := strings.Index(line, "//"); j >= 0 {
+			line = line[:j]
+		}
+		line = strings.TrimSpace(line)
+		var arg string
+		switch {
+		case line == "":
+			continue
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case inBlock:
+			arg = line
+		case strings.HasPrefix(line, "use") && strings.TrimSpace(line[3:]) == "(":
+			inBlock = true
+			continue
+		case strings.HasPrefix(line, "use ") || strings.HasPrefix(line, "use\t"):
+			arg = strings.TrimSpace(line[4:])
+		default:
+			continue // go, toolchain, replace, godebug
+		}
+		dir, err := unquotePath(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(base, dir)
+		}
+		dirs = append(dirs, filepath.Clean(dir))
+	}
+	return dirs, nil
+}
+
+func unquotePath(s string) (string, error) {
+	if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "`") {
+		return strconv.Unquote(s)
+	}
+	if strings.ContainsAny(s, " \t") {
+		return "", fmt.Errorf("invalid use path %q", s)
+	}
+	return s, nil
+}
+
+// Folders returns the workspace folders for root: root itself, every module
+// of its go.work (if any), then extra. Paths are absolute and unique.
+func Folders(root string, extra []string) ([]string, error) {
+	var folders []string
+	seen := make(map[string]bool)
+	add := func(dir string) error {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		if !seen[abs] {
+			seen[abs] = true
+			folders = append(folders, abs)
+		}
+		return nil
+	}
+	if err := add(root); err != nil {
+		return nil, err
+	}
+	workFile, search, err := goWork()
+	if err != nil {
+		return nil, err
+	}
+	if search && exists(filepath.Join(root, "go.work")) {
+		workFile = filepath.Join(root, "go.work")
+	}
+	if workFile != "" {
+		modules, err := ParseGoWork(workFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range modules {
+			if err := add(dir); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, dir := range extra {
+		if err := add(dir); err != nil {
+			return nil, err
+		}
+	}
+	return folders, nil
+}
+
+// goWork interprets GOWORK as the go command does: "off" disables
+// workspace mode, an absolute path names the go.work file to use, and
+// empty or "auto" means searching for go.work upward from the directory.
+func goWork() (file string, search bool, err error) {
+	switch v := os.Getenv("GOWORK"); v {
+	case "off":
+		return "", false, nil
+	case "", "auto":
+		return "", true, nil
+	default:
+		if !filepath.IsAbs(v) {
+			return "", false, fmt.Errorf("GOWORK=%s: not an absolute path", v)
+		}
+		return filepath.Clean(v), false, nil
+	}
+}
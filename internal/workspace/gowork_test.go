@@ -0,0 +1,114 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseGoWork(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+		want    []string // Relative to the go.work directory
+	}{
+		{
+			name:    "use block",
+			content: "go 1.22\n\nuse (\n\t./a\n\t./b/c\n)\n",
+			want:    []string{"a", "b/c"},
+		},
+		{
+			name:    "single use",
+			content: "go 1.22\nuse ./a\nuse\t../b\n",
+			want:    []string{"a", "../b"},
+		},
+		{
+			name:    "quoted paths",
+			content: "use (\n\t\"./with space\"\n\t`./raw`\n)\nuse \"./single\"\n",
+			want:    []string{"with space", "raw", "single"},
+		},
+		{
+			name:    "trailing comments",
+			content: "use ./a // the main module\nuse ( // modules\n\t./b // second\n) // done\n",
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "other directives",
+			content: "go 1.22\ntoolchain go1.22.1\nreplace example.com/x => ./x\ngodebug default=go1.21\nuse .\n",
+			want:    []string{"."},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "go.work")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseGoWork(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := make([]string, len(tt.want))
+			for i, rel := range tt.want {
+				want[i] = filepath.Join(dir, rel)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("modules = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestParseGoWorkInvalidPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "go.work")
+	if err := os.WriteFile(path, []byte("use ./a b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseGoWork(path); err == nil {
+		t.Error("unquoted path with a space: no error")
+	}
+}
+
+// writeTree creates files (relative path to content) under a new directory
+// and returns it.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFolders(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"go.work":  "use (\n\t.\n\t./a\n\t./b\n)\n",
+		"go.mod":   "module example.com/root\n",
+		"a/go.mod": "module example.com/a\n",
+		"b/go.mod": "module example.com/b\n",
+	})
+	extra := filepath.Join(dir, "extra")
+	for _, tt := range []struct {
+		gowork string
+		want   []string
+	}{
+		{"", []string{dir, filepath.Join(dir, "a"), filepath.Join(dir, "b"), extra}},
+		{"off", []string{dir, extra, filepath.Join(dir, "a")}},
+	} {
+		t.Setenv("GOWORK", tt.gowork)
+		got, err := Folders(dir, []string{extra, filepath.Join(dir, "a")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GOWORK=%s: folders = %q, want %q", tt.gowork, got, tt.want)
+		}
+	}
+}
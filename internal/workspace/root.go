@@ -2,13 +2,27 @@ package workspace
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 )
 
-// DetectRoot walks upward from start to find go.work or go.mod.
-// If none found, it returns the absolute start path.
+// DetectRoot walks upward from start to find the workspace root: the
+// directory of the enclosing go.work if there is one (like the go command,
+// it keeps looking past go.mod files), otherwise of the nearest go.mod.
+// If none found, it returns the absolute start path. GOWORK=off ignores
+// go.work files, and GOWORK set to a file makes its directory the root.
 func DetectRoot(start string) (string, error) {
+	workFile, search, err := goWork()
+	if err != nil {
+		return "", err
+	}
+	if workFile != "" {
+		if !exists(workFile) {
+			return "", fmt.Errorf("GOWORK=%s: no such file", workFile)
+		}
+		return filepath.Dir(workFile), nil
+	}
 	if start == "" {
 		start = "."
 	}
@@ -16,17 +30,25 @@ func DetectRoot(start string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	module := ""
 	path := abs
 	for {
-		if exists(filepath.Join(path, "go.work")) || exists(filepath.Join(path, "go.mod")) {
+		if search && exists(filepath.Join(path, "go.work")) {
 			return path, nil
 		}
+		if module == "" && exists(filepath.Join(path, "go.mod")) {
+			module = path
+		}
 		parent := filepath.Dir(path)
 		if parent == path {
-			return abs, nil
+			break
 		}
 		path = parent
 	}
+	if module != "" {
+		return module, nil
+	}
+	return abs, nil
 }
 
 func exists(path string) bool {
@@ -0,0 +1,54 @@
+package workspace
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectRoot(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"go.work":            "use ./mod\n",
+		"mod/go.mod":         "module example.com/mod\n",
+		"mod/pkg/x.go":       "package pkg\n",
+		"other/go.work":      "use ./sub\n",
+		"other/sub/go.mod":   "module example.com/sub\n",
+		"other/sub/pkg/w.go": "package pkg\n",
+		"plain/go.mod":       "module example.com/plain\n",
+		"plain/nested/y.go":  "package nested\n",
+	})
+	pkg := filepath.Join(dir, "mod", "pkg")
+	for _, tt := range []struct {
+		gowork string
+		start  string
+		want   string
+	}{
+		// go.work wins over the nearer go.mod.
+		{"", pkg, dir},
+		{"auto", pkg, dir},
+		{"off", pkg, filepath.Join(dir, "mod")},
+		{"", filepath.Join(dir, "plain", "nested"), dir},
+		// An explicit go.work applies wherever the search starts.
+		{filepath.Join(dir, "other", "go.work"), pkg, filepath.Join(dir, "other")},
+		{filepath.Join(dir, "other", "go.work"), filepath.Join(dir, "other", "sub", "pkg"), filepath.Join(dir, "other")},
+	} {
+		t.Setenv("GOWORK", tt.gowork)
+		got, err := DetectRoot(tt.start)
+		if err != nil {
+			t.Errorf("GOWORK=%s %s: %v", tt.gowork, tt.start, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("GOWORK=%s %s: root = %s, want %s", tt.gowork, tt.start, got, tt.want)
+		}
+	}
+}
+
+func TestDetectRootInvalidGOWORK(t *testing.T) {
+	dir := t.TempDir()
+	for _, gowork := range []string{"relative/go.work", filepath.Join(dir, "missing.work")} {
+		t.Setenv("GOWORK", gowork)
+		if _, err := DetectRoot(dir); err == nil {
+			t.Errorf("GOWORK=%s: no error", gowork)
+		}
+	}
+}